@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/services"
+)
+
+// runCheck runs the check subcommand and returns the exit code: 0 if the
+// index is consistent or was repaired, 1 if it is not, 2 on errors.
+//
+// The process does not share the update mutex of a running server, so the
+// repair takes the update lease instead: no instance runs an update while it
+// holds the lease, and it is refused while a server leads.
+func runCheck(ctx context.Context, cSVC *services.ComicsService, election *services.ElectionService,
+	audit *services.AuditService, args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	repair := fs.Bool("repair", false, "reindex the comics the check reports")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var report domain.ConsistencyReport
+	var err error
+	if *repair {
+		report, err = repairConsistency(ctx, cSVC, election, audit)
+	} else {
+		report, err = cSVC.CheckConsistency(ctx)
+	}
+	if err != nil {
+		slog.Error("error check consistency", "error", err)
+		return 2
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(report); err != nil {
+		slog.Error("error write report", "error", err)
+		return 2
+	}
+	if report.Consistent() || *repair {
+		return 0
+	}
+	return 1
+}
+
+// repairConsistency repairs the index as the update leader and records the
+// repair in the audit log like the admin endpoint does.
+func repairConsistency(ctx context.Context, cSVC *services.ComicsService, election *services.ElectionService,
+	audit *services.AuditService) (report domain.ConsistencyReport, err error) {
+	event := domain.AuditEvent{
+		Actor:  "cli",
+		Action: domain.AuditActionRepair,
+	}
+	defer func() {
+		audit.Record(ctx, event)
+	}()
+
+	campaignCtx, stopCampaign := context.WithCancel(ctx)
+	campaignDone := make(chan struct{})
+	defer func() {
+		// gives the lease up before the process exits
+		stopCampaign()
+		<-campaignDone
+	}()
+	election.Campaign(campaignCtx)
+	go func() {
+		election.Run(campaignCtx)
+		close(campaignDone)
+	}()
+	leadCtx, cancel, err := election.Lead(ctx)
+	if err != nil {
+		event.Outcome, event.Details = domain.AuditOutcomeDenied, err.Error()
+		return domain.ConsistencyReport{}, fmt.Errorf("error take update lease, repair through the admin API of the server: %w", err)
+	}
+	defer cancel()
+
+	report, err = cSVC.RepairConsistency(leadCtx)
+	if err != nil {
+		event.Outcome, event.Details = domain.AuditOutcomeFailure, err.Error()
+		return domain.ConsistencyReport{}, err
+	}
+	event.Outcome, event.Details = domain.AuditOutcomeSuccess, fmt.Sprintf("repaired: %v", report.Repaired)
+	return report, nil
+}
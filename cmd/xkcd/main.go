@@ -2,100 +2,261 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
+	"os"
 	"os/signal"
 	"sync"
 	"syscall"
 	"time"
 	"yadro-project/internal/adapters/handler"
 	"yadro-project/internal/adapters/index"
+	"yadro-project/internal/adapters/limiter"
 	"yadro-project/internal/adapters/repository"
 	"yadro-project/internal/config"
+	"yadro-project/internal/core/ports"
 	"yadro-project/internal/core/services"
+	"yadro-project/internal/logging"
+	"yadro-project/internal/metrics"
+	"yadro-project/internal/tracing"
 	"yadro-project/pkg/words"
 	"yadro-project/pkg/xkcd"
+
+	"github.com/robfig/cron/v3"
 )
 
 func main() {
 	var cfgPath string
+	var printConfig bool
 	flag.StringVar(&cfgPath, "c", "", "parse file path config")
+	flag.BoolVar(&printConfig, "print-config", false, "print the effective config with secrets redacted and exit")
 	flag.Parse()
-	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGHUP)
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM)
 	defer cancel()
 	cfg, err := config.NewConfig(cfgPath)
 	if err != nil {
-		log.Fatal(err)
+		fatal("error load config", err)
+	}
+	if printConfig {
+		out, err := cfg.YAML()
+		if err != nil {
+			fatal("error print config", err)
+		}
+		os.Stdout.Write(out)
+		return
+	}
+	logger, err := logging.New(os.Stderr, cfg.LogCFG.Format, cfg.LogCFG.Level)
+	if err != nil {
+		fatal("error create logger", err)
+	}
+	slog.SetDefault(logger)
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Exporter:    cfg.TraceCFG.Exporter,
+		Endpoint:    cfg.TraceCFG.Endpoint,
+		Insecure:    cfg.TraceCFG.Insecure,
+		ServiceName: cfg.TraceCFG.ServiceName,
+		SampleRatio: cfg.TraceCFG.SampleRatio,
+	})
+	if err != nil {
+		fatal("error setup tracing", err)
 	}
 
 	db, err := repository.NewPostgresConn(ctx, cfg.DbCFG)
 	if err != nil {
-		log.Fatal(err)
+		fatal("error connect repository", err)
 	}
 	idx, err := index.NewPostgresConn(ctx, cfg.DbCFG)
 	if err != nil {
-		log.Fatal(err)
+		fatal("error connect index", err)
 	}
 	stemmer := words.NewSnowBallStem()
-	parser := xkcd.NewXkcdParse(cfg.AppCFG.SourceURL, cfg.AppCFG.Parallel, stemmer)
-	cSVC := services.NewComicsService(db, parser, idx, stemmer)
+	parser := xkcd.NewXkcdParse(cfg.AppCFG.SourceURL, cfg.AppCFG.Parallel, stemmer, metrics.Recorder{})
+	cSVC := services.NewComicsService(db, parser, idx, stemmer, cfg.UpdCFG.FullCheckInterval, cfg.SrchCFG.CacheSize, cfg.SrchCFG.CacheTTL, metrics.Recorder{})
+	rSVC := services.NewRelatedService(db, cSVC)
+	auditDB, err := repository.NewAuditPostgresRepository(ctx, cfg.DbCFG)
+	if err != nil {
+		fatal("error connect audit log", err)
+	}
+	auSVC := services.NewAuditService(auditDB)
+	leaseDB, err := repository.NewLeasePostgresRepository(ctx, cfg.DbCFG)
+	if err != nil {
+		fatal("error connect leases", err)
+	}
+	// xkcd [-c config] check [--repair] сверяет индекс с комиксами и выходит
+	if flag.Arg(0) == "check" {
+		election := services.NewElectionService(leaseDB, cfg.SrvCFG.InstanceID+"/check", cfg.UpdCFG.LeaseTTL)
+		code := runCheck(ctx, cSVC, election, auSVC, flag.Args()[1:])
+		if err = shutdownTracing(ctx); err != nil {
+			slog.Error("error flush traces", "error", err)
+		}
+		os.Exit(code)
+	}
+
+	dailyDB, err := repository.NewDailyPostgresRepository(ctx, cfg.DbCFG)
+	if err != nil {
+		fatal("error connect daily comics", err)
+	}
+	dSVC := services.NewDiscoveryService(db, dailyDB, stemmer, cfg.DailyCFG.NoRepeatDays)
 
 	authDB, err := repository.NewAuthJSONRepository("users.json")
 	if err != nil {
-		log.Fatal(err)
+		fatal("error open users", err)
 	}
 	aSVC := services.NewAuthService(authDB, cfg.AuthCFG.TokenMaxTime)
-	lSVC := services.NewLimitService(cfg.SrvCFG.RateLimit, cfg.SrvCFG.ConcurrencyLimit)
+	securityLog, err := repository.NewSecurityLogFile(cfg.AuthCFG.LoginGuard.SecurityLogFile)
+	if err != nil {
+		fatal("error open security log", err)
+	}
+	defer securityLog.Close()
+	gSVC := services.NewLoginGuardService(services.LoginGuardPolicy{
+		MaxFailuresPerIP:      cfg.AuthCFG.LoginGuard.MaxFailuresPerIP,
+		MaxFailuresPerAccount: cfg.AuthCFG.LoginGuard.MaxFailuresPerAccount,
+		FreeAttempts:          cfg.AuthCFG.LoginGuard.FreeAttempts,
+		BaseDelay:             cfg.AuthCFG.LoginGuard.BaseDelay,
+		MaxDelay:              cfg.AuthCFG.LoginGuard.MaxDelay,
+		LockoutTime:           cfg.AuthCFG.LoginGuard.LockoutTime,
+		FailureWindow:         cfg.AuthCFG.LoginGuard.FailureWindow,
+	}, securityLog)
+	var rl ports.RateLimiter
+	switch cfg.SrvCFG.RateLimitBackend {
+	case "postgres":
+		pgLimiter, err := limiter.NewPostgresLimiter(ctx, cfg.DbCFG)
+		if err != nil {
+			fatal("error create rate limiter", err)
+		}
+		go pgLimiter.Run(ctx, cfg.SrvCFG.LimiterIdleTimeout)
+		rl = pgLimiter
+	case "memory", "":
+		memLimiter := limiter.NewMemoryLimiter(cfg.SrvCFG.LimiterIdleTimeout, cfg.SrvCFG.LimiterMaxEntries)
+		go memLimiter.Run(ctx)
+		rl = memLimiter
+	default:
+		fatal("error create rate limiter", fmt.Errorf("unknown rate limit backend %q", cfg.SrvCFG.RateLimitBackend))
+	}
+	admission := services.NewAdmission(cfg.SrvCFG.ConcurrencyLimit, cfg.SrvCFG.ConcurrencyQueue, cfg.SrvCFG.ConcurrencyTimeout, metrics.Recorder{})
+	lSVC := services.NewLimitService(ratePolicies(cfg.SrvCFG), rl, admission)
 	mutex := &sync.Mutex{}
-	srv := NewServer(ctx, *cSVC, *lSVC, *aSVC, mutex, fmt.Sprintf(":%d", cfg.SrvCFG.Port))
+	session := handler.SessionOptions{
+		Enabled:        cfg.AuthCFG.Session.Enabled,
+		CookieName:     cfg.AuthCFG.Session.CookieName,
+		CSRFCookieName: cfg.AuthCFG.Session.CSRFCookieName,
+		Secure:         cfg.AuthCFG.Session.Secure,
+	}
+	legacy := handler.LegacyOptions{
+		Enabled:      cfg.SrvCFG.LegacyRoutes.Enabled,
+		DeprecatedAt: cfg.SrvCFG.LegacyRoutes.DeprecatedAt,
+		Sunset:       cfg.SrvCFG.LegacyRoutes.Sunset,
+	}
+	historyDB, err := repository.NewUpdateHistoryPostgresRepository(ctx, cfg.DbCFG)
+	if err != nil {
+		fatal("error connect update history", err)
+	}
+	schedule, err := cron.ParseStandard(cfg.UpdCFG.Schedule)
+	if err != nil {
+		fatal("error parse update schedule", err)
+	}
+	eSVC := services.NewElectionService(leaseDB, cfg.SrvCFG.InstanceID, cfg.UpdCFG.LeaseTTL)
+	campaignDone := make(chan struct{})
+	go func() {
+		eSVC.Run(ctx)
+		close(campaignDone)
+	}()
+	sSVC := services.NewSchedulerService(cSVC, eSVC, mutex, schedule, cfg.UpdCFG.Jitter, historyDB, auSVC, metrics.Recorder{})
+	go sSVC.Run(ctx)
+	hSVC := services.NewHealthService(map[string]ports.Pinger{
+		"repository": db,
+		"index":      idx,
+	}, cSVC)
+	// запросы живут дольше сигнала, пока сервер дренируется
+	serveCtx, stopServe := context.WithCancel(context.Background())
+	defer stopServe()
+	srv := NewServer(serveCtx, cSVC, rSVC, dSVC, lSVC, aSVC, gSVC, auSVC, eSVC, sSVC, hSVC, session, legacy, mutex, fmt.Sprintf(":%d", cfg.SrvCFG.Port))
 	wg := sync.WaitGroup{}
 	wg.Add(2)
 	go func() {
-		if err = srv.ListenAndServe(); err != nil {
-			log.Fatal(err)
+		slog.Info("server started", "addr", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("error serve", err)
 		}
 	}()
-	<-ctx.Done()
-	//ждем завершения последнего апдейта
-	mutex.Lock()
-	ctx, cancel = context.WithTimeout(context.Background(), time.Second*30)
-	defer cancel()
-	if err = srv.Shutdown(ctx); err != nil {
-		log.Fatal(err)
+	rld := &reloader{
+		path:   cfgPath,
+		cfg:    cfg,
+		limit:  lSVC,
+		auth:   aSVC,
+		parser: parser,
+		audit:  auSVC,
 	}
-
-}
-
-func NewServer(ctx context.Context, cSVC services.ComicsService, lSVC services.LimitService, aSVC services.AuthService, mutex *sync.Mutex, addr string) *http.Server {
-	router := http.NewServeMux()
-	c := handler.NewComicsHandler(cSVC, mutex)
-	l := handler.NewLimitHandler(lSVC, aSVC)
-	a := handler.NewAuthHandler(aSVC)
-	router.Handle("GET /pics", a.AuthMiddleware(http.HandlerFunc(c.GetComics)))
-	router.Handle("POST /update", l.LimitingMiddleware(http.HandlerFunc(c.UpdateComics)))
-	router.HandleFunc("POST /login", a.LoginHandler)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 	go func() {
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case <-time.After(time.Hour * 24):
-				if mutex.TryLock() {
-					_, _ = cSVC.UpdateComics(ctx)
-					mutex.Unlock()
-				}
+			case <-hup:
+				rld.reload(ctx)
 			}
 		}
 	}()
+	<-ctx.Done()
+	slog.Info("shutting down", "drain_delay", cfg.SrvCFG.DrainDelay)
+	// даем балансировщику заметить, что сервер больше не готов
+	hSVC.Drain()
+	time.Sleep(cfg.SrvCFG.DrainDelay)
+	//ждем завершения последнего апдейта
+	mutex.Lock()
+	ctx, cancel = context.WithTimeout(context.Background(), cfg.SrvCFG.ShutdownTimeout)
+	defer cancel()
+	if err = srv.Shutdown(ctx); err != nil {
+		fatal("error shutdown", err)
+	}
+	stopServe()
+	// отдаем лидерство, чтобы другая реплика не ждала истечения аренды
+	<-campaignDone
+	if err = shutdownTracing(ctx); err != nil {
+		slog.Error("error flush traces", "error", err)
+	}
+	slog.Info("server stopped")
+}
+
+func NewServer(ctx context.Context, cSVC *services.ComicsService, rSVC *services.RelatedService, dSVC *services.DiscoveryService, lSVC *services.LimitService, aSVC *services.AuthService, gSVC *services.LoginGuardService, auSVC *services.AuditService, eSVC *services.ElectionService, sSVC *services.SchedulerService, hSVC *services.HealthService, session handler.SessionOptions, legacy handler.LegacyOptions, mutex *sync.Mutex, addr string) *http.Server {
+	c := handler.NewComicsHandler(cSVC, rSVC, dSVC, auSVC, eSVC, mutex)
+	l := handler.NewLimitHandler(lSVC)
+	a := handler.NewAuthHandler(aSVC, gSVC, auSVC, session)
+	adm := handler.NewAdminHandler(auSVC, sSVC, cSVC, eSVC, mutex)
+	router := handler.NewRouter(c, a, l, adm, handler.NewDocsHandler(), handler.NewHealthHandler(hSVC), legacy)
 	return &http.Server{
-		Addr:    addr,
-		Handler: router,
+		Addr:     addr,
+		Handler:  router,
+		ErrorLog: slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
 		BaseContext: func(_ net.Listener) context.Context {
 			return ctx
 		},
 	}
 
 }
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+func ratePolicies(cfg config.ServerConfig) []services.RoutePolicy {
+	policies := make([]services.RoutePolicy, 0, len(cfg.RatePolicies))
+	for _, p := range cfg.RatePolicies {
+		policies = append(policies, services.RoutePolicy{
+			Route: p.Route,
+			Role:  p.Role,
+			Key:   p.Key,
+			Limit: p.Limit,
+			Per:   p.Per,
+			Burst: p.Burst,
+		})
+	}
+	return policies
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"yadro-project/internal/config"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/services"
+	"yadro-project/internal/logging"
+	"yadro-project/pkg/xkcd"
+)
+
+// reloadable are the settings applied on SIGHUP, the rest need a restart.
+var reloadable = map[string]bool{
+	"server.rate_policies":       true,
+	"server.concurrency_limit":   true,
+	"server.concurrency_queue":   true,
+	"server.concurrency_timeout": true,
+	"auth.token_max_time":        true,
+	"app.parallel":               true,
+	"log.level":                  true,
+}
+
+// policySeeds only make the policy of POST /update when the file has no
+// rate_policies, so a change to them is applied as a change of rate_policies.
+var policySeeds = map[string]bool{
+	"server.rate_limit": true,
+	"server.rate_burst": true,
+}
+
+type reloader struct {
+	path   string
+	cfg    config.Config
+	limit  *services.LimitService
+	auth   *services.AuthService
+	parser *xkcd.XkcdParse
+	audit  *services.AuditService
+}
+
+// reload re-reads the config and applies the reloadable settings. Changes to
+// other settings are logged and ignored, so they are reported again on the
+// next reload until the server is restarted.
+func (rl *reloader) reload(ctx context.Context) {
+	event := domain.AuditEvent{
+		Actor:   "signal",
+		Action:  domain.AuditActionConfigReload,
+		Outcome: domain.AuditOutcomeSuccess,
+	}
+	defer func() {
+		rl.audit.Record(ctx, event)
+	}()
+
+	next, err := config.NewConfig(rl.path)
+	if err == nil {
+		err = logging.SetLevel(next.LogCFG.Level)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "error reload config", "error", err)
+		event.Outcome, event.Details = domain.AuditOutcomeFailure, err.Error()
+		return
+	}
+
+	var applied, rejected []string
+	diff := config.Diff(rl.cfg, next)
+	policiesChanged := slices.Contains(diff, "server.rate_policies")
+	for _, setting := range diff {
+		if reloadable[setting] || policySeeds[setting] && policiesChanged {
+			applied = append(applied, setting)
+			continue
+		}
+		if policySeeds[setting] {
+			rejected = append(rejected, setting)
+			slog.WarnContext(ctx, "setting is ignored while rate_policies are set", "setting", setting)
+			continue
+		}
+		rejected = append(rejected, setting)
+		slog.WarnContext(ctx, "setting can't be changed at runtime, restart to apply it", "setting", setting)
+	}
+
+	rl.limit.SetPolicies(ratePolicies(next.SrvCFG))
+	rl.limit.SetConcurrency(next.SrvCFG.ConcurrencyLimit, next.SrvCFG.ConcurrencyQueue, next.SrvCFG.ConcurrencyTimeout)
+	rl.auth.SetTokenMaxTime(next.AuthCFG.TokenMaxTime)
+	rl.parser.SetParallel(next.AppCFG.Parallel)
+
+	for _, setting := range applied {
+		config.Copy(&rl.cfg, next, setting)
+	}
+
+	event.Details = fmt.Sprintf("applied: [%s], rejected: [%s]", strings.Join(applied, ", "), strings.Join(rejected, ", "))
+	slog.InfoContext(ctx, "config reloaded", "applied", applied, "rejected", rejected)
+}
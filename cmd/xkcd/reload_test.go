@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"yadro-project/internal/adapters/limiter"
+	"yadro-project/internal/config"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/services"
+	"yadro-project/pkg/xkcd"
+)
+
+type memAudit struct {
+	events []domain.AuditEvent
+}
+
+func (a *memAudit) Append(ctx context.Context, event domain.AuditEvent) error {
+	a.events = append(a.events, event)
+	return nil
+}
+
+func (a *memAudit) Find(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent, error) {
+	return a.events, nil
+}
+
+const reloadConfig = `
+database:
+  user: "xkcd"
+  database: "comics"
+server:
+  port: %PORT%
+  concurrency_limit: %LIMIT%
+`
+
+func writeReloadConfig(t *testing.T, path, port, limit string) {
+	t.Helper()
+	data := strings.NewReplacer("%PORT%", port, "%LIMIT%", limit).Replace(reloadConfig)
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloader_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeReloadConfig(t, path, "9000", "10")
+	cfg, err := config.NewConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admission := services.NewAdmission(cfg.SrvCFG.ConcurrencyLimit, 0, 0, nil)
+	audit := &memAudit{}
+	rl := &reloader{
+		path:   path,
+		cfg:    cfg,
+		limit:  services.NewLimitService(nil, limiter.NewMemoryLimiter(0, 0), admission),
+		auth:   services.NewAuthService(nil, time.Minute),
+		parser: xkcd.NewXkcdParse("xkcd.com", 1, nil, nil),
+		audit:  services.NewAuditService(audit),
+	}
+
+	writeReloadConfig(t, path, "8080", "20")
+	rl.reload(context.Background())
+	if len(audit.events) != 1 {
+		t.Fatalf("got %d audit events, want 1", len(audit.events))
+	}
+	want := "applied: [server.concurrency_limit], rejected: [server.port]"
+	if e := audit.events[0]; e.Outcome != domain.AuditOutcomeSuccess || e.Details != want {
+		t.Errorf("got %s %q, want success %q", e.Outcome, e.Details, want)
+	}
+	if got := admission.Stats().Limit; got != 20 {
+		t.Errorf("got concurrency limit %d, want 20 applied", got)
+	}
+	if rl.cfg.SrvCFG.ConcurrencyLimit != 20 || rl.cfg.SrvCFG.Port != 9000 {
+		t.Errorf("got limit %d and port %d in the running config, want 20 and 9000",
+			rl.cfg.SrvCFG.ConcurrencyLimit, rl.cfg.SrvCFG.Port)
+	}
+
+	// the rejected change is reported until a restart, the applied one is not
+	rl.reload(context.Background())
+	want = "applied: [], rejected: [server.port]"
+	if e := audit.events[1]; e.Details != want {
+		t.Errorf("got %q on the second reload, want %q", e.Details, want)
+	}
+}
+
+const policyConfig = `
+database:
+  user: "xkcd"
+  database: "comics"
+server:
+  rate_limit: %LIMIT%
+%POLICIES%`
+
+func TestReloader_RateLimitSeedsPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policies string
+		want     string
+	}{
+		{"without policies", "", "applied: [server.rate_limit, server.rate_policies], rejected: []"},
+		{"with policies", "  rate_policies:\n    - route: \"GET /pics\"\n      limit: 5\n      per: 1s\n",
+			"applied: [], rejected: [server.rate_limit]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			write := func(limit string) {
+				data := strings.NewReplacer("%LIMIT%", limit, "%POLICIES%", tt.policies).Replace(policyConfig)
+				if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			write("10")
+			cfg, err := config.NewConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			audit := &memAudit{}
+			rl := &reloader{
+				path:   path,
+				cfg:    cfg,
+				limit:  services.NewLimitService(nil, limiter.NewMemoryLimiter(0, 0), services.NewAdmission(1, 0, 0, nil)),
+				auth:   services.NewAuthService(nil, time.Minute),
+				parser: xkcd.NewXkcdParse("xkcd.com", 1, nil, nil),
+				audit:  services.NewAuditService(audit),
+			}
+
+			write("20")
+			rl.reload(context.Background())
+			if e := audit.events[0]; e.Details != tt.want {
+				t.Errorf("got %q, want %q", e.Details, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/services"
+)
+
+var ( //errors
+	errLimitIsInvalid  = errors.New("query \"limit\" must be a non-negative integer")
+	errOffsetIsInvalid = errors.New("query \"offset\" must be a non-negative integer")
+)
+
+type AdminHandler struct {
+	audit     *services.AuditService
+	scheduler *services.SchedulerService
+	comics    *services.ComicsService
+	election  *services.ElectionService
+	mutex     *sync.Mutex
+}
+
+func NewAdminHandler(audit *services.AuditService, scheduler *services.SchedulerService, comics *services.ComicsService, election *services.ElectionService, mutex *sync.Mutex) *AdminHandler {
+	return &AdminHandler{
+		audit:     audit,
+		scheduler: scheduler,
+		comics:    comics,
+		election:  election,
+		mutex:     mutex,
+	}
+}
+
+func (h *AdminHandler) GetAudit(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		HandleError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	events, err := h.audit.Find(r.Context(), filter)
+	if err != nil {
+		HandleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, events)
+}
+
+func (h *AdminHandler) GetUpdates(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			HandleError(w, r, http.StatusBadRequest, errLimitIsInvalid)
+			return
+		}
+	}
+	runs, err := h.scheduler.History(r.Context(), limit)
+	if err != nil {
+		HandleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, runs)
+}
+
+func (h *AdminHandler) GetConsistency(w http.ResponseWriter, r *http.Request) {
+	report, err := h.comics.CheckConsistency(r.Context())
+	if err != nil {
+		HandleError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, report)
+}
+
+// RepairConsistency runs like an update: only on the leader and never next
+// to another update, so it never reindexes comics an update is writing.
+func (h *AdminHandler) RepairConsistency(w http.ResponseWriter, r *http.Request) {
+	event := domain.AuditEvent{
+		Actor:  emailFromContext(r.Context()),
+		IP:     clientIP(r),
+		Action: domain.AuditActionRepair,
+	}
+	ctx, cancel, err := h.election.Lead(r.Context())
+	if err != nil {
+		event.Outcome, event.Details = domain.AuditOutcomeDenied, err.Error()
+		h.audit.Record(r.Context(), event)
+		notLeader(w, r, h.election, err)
+		return
+	}
+	defer cancel()
+	if !h.mutex.TryLock() {
+		event.Outcome, event.Details = domain.AuditOutcomeDenied, errAccepted.Error()
+		h.audit.Record(r.Context(), event)
+		HandleError(w, r, http.StatusAccepted, errAccepted)
+		return
+	}
+	defer h.mutex.Unlock()
+	report, err := h.comics.RepairConsistency(ctx)
+	if err != nil {
+		event.Outcome, event.Details = domain.AuditOutcomeFailure, err.Error()
+	} else {
+		event.Outcome, event.Details = domain.AuditOutcomeSuccess, fmt.Sprintf("repaired: %v", report.Repaired)
+	}
+	h.audit.Record(r.Context(), event)
+	if err != nil {
+		HandleError(w, r, http.StatusInternalServerError, fmt.Errorf("error repair consistency: %w", err))
+		return
+	}
+	writeJSON(w, report)
+}
+
+// Reindex runs like an update: only on the leader and never next to another
+// update.
+func (h *AdminHandler) Reindex(w http.ResponseWriter, r *http.Request) {
+	event := domain.AuditEvent{
+		Actor:  emailFromContext(r.Context()),
+		IP:     clientIP(r),
+		Action: domain.AuditActionReindex,
+	}
+	ctx, cancel, err := h.election.Lead(r.Context())
+	if err != nil {
+		event.Outcome, event.Details = domain.AuditOutcomeDenied, err.Error()
+		h.audit.Record(r.Context(), event)
+		notLeader(w, r, h.election, err)
+		return
+	}
+	defer cancel()
+	if !h.mutex.TryLock() {
+		event.Outcome, event.Details = domain.AuditOutcomeDenied, errAccepted.Error()
+		h.audit.Record(r.Context(), event)
+		HandleError(w, r, http.StatusAccepted, errAccepted)
+		return
+	}
+	defer h.mutex.Unlock()
+	meta, err := h.comics.ReindexComics(ctx)
+	if err != nil {
+		event.Outcome, event.Details = domain.AuditOutcomeFailure, err.Error()
+	} else {
+		event.Outcome, event.Details = domain.AuditOutcomeSuccess, fmt.Sprintf("generation: %d, comics: %d", meta.Generation, meta.Comics)
+	}
+	h.audit.Record(r.Context(), event)
+	if err != nil {
+		HandleError(w, r, http.StatusInternalServerError, fmt.Errorf("error reindex comics: %w", err))
+		return
+	}
+	writeJSON(w, meta)
+}
+
+func parseAuditFilter(r *http.Request) (domain.AuditFilter, error) {
+	queries := r.URL.Query()
+	filter := domain.AuditFilter{
+		Actor:   queries.Get("actor"),
+		IP:      queries.Get("ip"),
+		Action:  queries.Get("action"),
+		Outcome: queries.Get("outcome"),
+	}
+	var err error
+	if v := queries.Get("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return domain.AuditFilter{}, fmt.Errorf("error parse query \"from\": %w", err)
+		}
+	}
+	if v := queries.Get("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return domain.AuditFilter{}, fmt.Errorf("error parse query \"to\": %w", err)
+		}
+	}
+	if v := queries.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 0 {
+			return domain.AuditFilter{}, errLimitIsInvalid
+		}
+	}
+	if v := queries.Get("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil || filter.Offset < 0 {
+			return domain.AuditFilter{}, errOffsetIsInvalid
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/services"
+)
+
+func TestAdminHandler_RepairOnFollower(t *testing.T) {
+	audit := &auditEvents{}
+	election := services.NewElectionService(heldLease{holder: "replica-2"}, "replica-1", time.Minute)
+	election.Campaign(context.Background())
+	h := NewAdminHandler(services.NewAuditService(audit), nil, nil, election, &sync.Mutex{})
+
+	rec := httptest.NewRecorder()
+	h.RepairConsistency(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/consistency/repair", nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("got status %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body.String())
+	}
+	var body struct {
+		Details struct {
+			Leader string `json:"leader"`
+		} `json:"details"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Details.Leader != "replica-2" {
+		t.Errorf("got leader %q, %v, want replica-2", body.Details.Leader, err)
+	}
+	if len(audit.events) != 1 || audit.events[0].Action != domain.AuditActionRepair || audit.events[0].Outcome != domain.AuditOutcomeDenied {
+		t.Errorf("got audit events %+v, want one denied repair", audit.events)
+	}
+}
@@ -1,38 +1,98 @@
 package handler
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 	"yadro-project/internal/core/domain"
 	"yadro-project/internal/core/services"
+	"yadro-project/internal/logging"
+	"yadro-project/internal/metrics"
 )
 
 const ( // headers
 	authorizationHeader = "Authorization"
+	retryAfterHeader    = "Retry-After"
+	warningHeader       = "Warning"
+	csrfHeader          = "X-CSRF-Token"
+)
+
+const ( // authorization schemes
+	bearerScheme = "Bearer"
+	beaverScheme = "Beaver"
 )
 
 var ( //errors
 	errAuthorizationHeaderIsEmpty = errors.New("authorization header is empty")
-	errHeaderIsNotRequiredMask    = errors.New("authorization header must be required by mask \"Beaver <token>\"")
+	errHeaderIsNotRequiredMask    = errors.New("authorization header must be required by mask \"Bearer <token>\"")
 	errUserIsNotExist             = errors.New("user is not exist")
+	errCSRFTokenInvalid           = errors.New("csrf token is missing or invalid")
+)
+
+type ctxKey int
+
+const (
+	emailKey ctxKey = iota
+	roleKey
 )
 
+type SessionOptions struct {
+	Enabled        bool
+	CookieName     string
+	CSRFCookieName string
+	Secure         bool
+}
+
 type AuthHandler struct {
-	svc services.AuthService
+	svc     *services.AuthService
+	guard   *services.LoginGuardService
+	audit   *services.AuditService
+	session SessionOptions
 }
 
-func NewAuthHandler(svc services.AuthService) *AuthHandler {
-	return &AuthHandler{svc: svc}
+func NewAuthHandler(svc *services.AuthService, guard *services.LoginGuardService, audit *services.AuditService, session SessionOptions) *AuthHandler {
+	return &AuthHandler{
+		svc:     svc,
+		guard:   guard,
+		audit:   audit,
+		session: session,
+	}
 }
 
 func (h *AuthHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	req := domain.LoginRequest{}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		HandleError(w, http.StatusBadRequest, fmt.Errorf("error decode json: %w", err))
+		HandleError(w, r, http.StatusBadRequest, fmt.Errorf("error decode json: %w", err))
+		return
+	}
+
+	ip := clientIP(r)
+	if wait, err := h.guard.Check(r.Context(), ip, req.Email); err != nil {
+		if errors.Is(err, services.ErrLoginLocked) {
+			metrics.LoginFailures.WithLabelValues("locked").Inc()
+		} else {
+			metrics.LoginFailures.WithLabelValues("throttled").Inc()
+		}
+		h.audit.Record(r.Context(), domain.AuditEvent{
+			Actor:   req.Email,
+			IP:      ip,
+			Action:  domain.AuditActionLogin,
+			Outcome: domain.AuditOutcomeDenied,
+			Details: err.Error(),
+		})
+		w.Header().Set(retryAfterHeader, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		HandleError(w, r, http.StatusTooManyRequests, err)
 		return
 	}
 
@@ -40,52 +100,202 @@ func (h *AuthHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		if errors.Is(err, services.ErrBadCredentials) {
-			HandleError(w, http.StatusUnauthorized, err)
+			h.guard.Failed(r.Context(), ip, req.Email)
+			metrics.LoginFailures.WithLabelValues("bad_credentials").Inc()
+			h.audit.Record(r.Context(), domain.AuditEvent{
+				Actor:   req.Email,
+				IP:      ip,
+				Action:  domain.AuditActionLoginFailed,
+				Outcome: domain.AuditOutcomeFailure,
+			})
+			HandleError(w, r, http.StatusUnauthorized, err)
 			return
 		}
-		HandleError(w, http.StatusInternalServerError, err)
+		h.guard.Released(r.Context(), ip, req.Email)
+		HandleError(w, r, http.StatusInternalServerError, err)
 		return
 	}
+	h.guard.Succeeded(r.Context(), ip, req.Email)
+	h.audit.Record(r.Context(), domain.AuditEvent{
+		Actor:   req.Email,
+		IP:      ip,
+		Action:  domain.AuditActionLogin,
+		Outcome: domain.AuditOutcomeSuccess,
+	})
 
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	resp := map[string]interface{}{
 		"token": t,
-	})
+	}
+	if h.session.Enabled {
+		csrf, err := newCSRFToken()
+		if err != nil {
+			HandleError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		h.setSessionCookies(w, t, csrf, h.svc.TokenMaxTime())
+		resp["csrf_token"] = csrf
+	}
+
+	writeJSON(w, resp)
+}
+
+func (h *AuthHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	h.setSessionCookies(w, "", "", -1)
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *AuthHandler) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token, err := getToken(r.Header.Get(authorizationHeader))
+		token, err := h.token(w, r)
 		if err != nil {
-			HandleError(w, http.StatusUnauthorized, err)
+			if errors.Is(err, errCSRFTokenInvalid) {
+				HandleError(w, r, http.StatusForbidden, err)
+				return
+			}
+			HandleError(w, r, http.StatusUnauthorized, err)
 			return
 		}
 
-		flag, err := h.svc.CheckToken(token)
+		email, err := h.svc.CheckToken(token)
 		if err != nil {
 			if errors.Is(err, services.ErrTokenInvalid) {
-				HandleError(w, http.StatusUnauthorized, err)
+				HandleError(w, r, http.StatusUnauthorized, err)
 				return
 			}
-			HandleError(w, http.StatusInternalServerError, err)
+			HandleError(w, r, http.StatusInternalServerError, err)
 			return
 		}
 
-		if !flag {
-			HandleError(w, http.StatusUnauthorized, errUserIsNotExist)
+		if email == "" {
+			HandleError(w, r, http.StatusUnauthorized, errUserIsNotExist)
+			return
+		}
+
+		role, err := h.svc.Role(email)
+		if err != nil {
+			HandleError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		if req := logging.FromContext(r.Context()); req != nil {
+			req.SetUser(email)
+		}
+		ctx := context.WithValue(r.Context(), emailKey, email)
+		ctx = context.WithValue(ctx, roleKey, role)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// AdminMiddleware must be wrapped by AuthMiddleware.
+func (h *AuthHandler) AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if roleFromContext(r.Context()) != domain.RoleAdmin {
+			HandleError(w, r, http.StatusForbidden, services.ErrForbidden)
 			return
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-func getToken(authHead string) (string, error) {
-	if authHead == "" {
+// token takes the token from the Authorization header or, if sessions are
+// enabled and the header is absent, from the session cookie. Cookie-based
+// requests that change state must carry the CSRF token in X-CSRF-Token.
+func (h *AuthHandler) token(w http.ResponseWriter, r *http.Request) (string, error) {
+	authHead := r.Header.Get(authorizationHeader)
+	if authHead != "" || !h.session.Enabled {
+		token, scheme, err := getToken(authHead)
+		if err != nil {
+			return "", err
+		}
+		if scheme == beaverScheme {
+			w.Header().Set(warningHeader, `299 - "Beaver authorization scheme is deprecated, use Bearer"`)
+		}
+		return token, nil
+	}
+
+	cookie, err := r.Cookie(h.session.CookieName)
+	if err != nil || cookie.Value == "" {
 		return "", errAuthorizationHeaderIsEmpty
 	}
+	if !isSafeMethod(r.Method) {
+		csrf, err := r.Cookie(h.session.CSRFCookieName)
+		header := r.Header.Get(csrfHeader)
+		if err != nil || header == "" || subtle.ConstantTimeCompare([]byte(csrf.Value), []byte(header)) != 1 {
+			return "", errCSRFTokenInvalid
+		}
+	}
+	return cookie.Value, nil
+}
+
+func (h *AuthHandler) setSessionCookies(w http.ResponseWriter, token, csrf string, maxAge time.Duration) {
+	age := int(maxAge.Seconds())
+	if maxAge < 0 {
+		age = -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.session.CookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   age,
+		HttpOnly: true,
+		Secure:   h.session.Secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.session.CSRFCookieName,
+		Value:    csrf,
+		Path:     "/",
+		MaxAge:   age,
+		Secure:   h.session.Secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
 
-	headerParts := strings.Split(authHead, " ")
-	if len(headerParts) != 2 || headerParts[0] != "Beaver" {
-		return "", errHeaderIsNotRequiredMask
+func getToken(authHead string) (string, string, error) {
+	if authHead == "" {
+		return "", "", errAuthorizationHeaderIsEmpty
+	}
+
+	headerParts := strings.Fields(authHead)
+	if len(headerParts) != 2 {
+		return "", "", errHeaderIsNotRequiredMask
+	}
+	switch {
+	case strings.EqualFold(headerParts[0], bearerScheme):
+		return headerParts[1], bearerScheme, nil
+	case headerParts[0] == beaverScheme:
+		return headerParts[1], beaverScheme, nil
+	}
+	return "", "", errHeaderIsNotRequiredMask
+}
+
+func emailFromContext(ctx context.Context) string {
+	email, _ := ctx.Value(emailKey).(string)
+	return email
+}
+
+func roleFromContext(ctx context.Context) string {
+	if role, ok := ctx.Value(roleKey).(string); ok {
+		return role
+	}
+	return domain.RoleAnonymous
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generate csrf token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
-	return headerParts[1], nil
+	return host
 }
@@ -1,23 +1,35 @@
 package handler
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"sync"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
 	"yadro-project/internal/core/services"
 )
 
 type ComicsHandler struct {
-	svc   services.ComicsService
-	mutex *sync.Mutex
+	svc      *services.ComicsService
+	related  *services.RelatedService
+	discover *services.DiscoveryService
+	audit    *services.AuditService
+	election *services.ElectionService
+	mutex    *sync.Mutex
 }
 
-func NewComicsHandler(svc services.ComicsService, mutex *sync.Mutex) *ComicsHandler {
+func NewComicsHandler(svc *services.ComicsService, related *services.RelatedService, discover *services.DiscoveryService, audit *services.AuditService, election *services.ElectionService, mutex *sync.Mutex) *ComicsHandler {
 	return &ComicsHandler{
-		svc:   svc,
-		mutex: mutex,
+		svc:      svc,
+		related:  related,
+		discover: discover,
+		audit:    audit,
+		election: election,
+		mutex:    mutex,
 	}
 }
 
@@ -25,44 +37,160 @@ var (
 	errQueryIsEmpty = errors.New("query \"search\" is empty")
 	errEncodeJSON   = errors.New("error encode json")
 	errAccepted     = errors.New("update already started")
+	errIDIsInvalid  = errors.New("comics id must be a positive integer")
+	errRelatedLimit = fmt.Errorf("query \"limit\" must be an integer from 1 to %d", services.MaxRelatedLimit)
 )
 
+const defaultRelatedLimit = 10
+
 func (h *ComicsHandler) GetComics(w http.ResponseWriter, r *http.Request) {
 	queries := r.URL.Query()
 	search := queries.Get("search")
 	if search == "" {
-		HandleError(w, http.StatusBadRequest, errQueryIsEmpty)
+		HandleError(w, r, http.StatusBadRequest, errQueryIsEmpty)
+		return
+	}
+	version, versionErr := h.svc.Version(r.Context())
+	if versionErr != nil {
+		slog.WarnContext(r.Context(), "error get comics version, response is not cacheable", "error", versionErr)
+	} else if notModified(w, r, version) {
 		return
 	}
 	comics, err := h.svc.GetComics(r.Context(), search)
 	if err != nil {
 		if errors.Is(err, services.ErrContextDone) {
-			HandleError(w, http.StatusServiceUnavailable, err)
+			HandleError(w, r, http.StatusServiceUnavailable, err)
 		} else {
-			HandleError(w, http.StatusInternalServerError, fmt.Errorf("error get comics from server: %w", err))
+			HandleError(w, r, http.StatusInternalServerError, fmt.Errorf("error get comics from server: %w", err))
 		}
 		return
 	}
 	for i := 0; i < len(comics); i++ {
 		comics[i].Keywords = nil
 	}
-	json.NewEncoder(w).Encode(comics)
+	if versionErr == nil {
+		setValidators(w, r, version)
+	}
+	writeJSON(w, comics)
+}
+
+func (h *ComicsHandler) GetRelated(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		HandleError(w, r, http.StatusBadRequest, errIDIsInvalid)
+		return
+	}
+	limit := defaultRelatedLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > services.MaxRelatedLimit {
+			HandleError(w, r, http.StatusBadRequest, errRelatedLimit)
+			return
+		}
+	}
+	version, versionErr := h.svc.Version(r.Context())
+	if versionErr != nil {
+		slog.WarnContext(r.Context(), "error get comics version, response is not cacheable", "error", versionErr)
+	} else if notModified(w, r, version) {
+		return
+	}
+	related, err := h.related.Related(r.Context(), id, limit)
+	if err != nil {
+		if errors.Is(err, ports.ErrIsNotExist) {
+			HandleError(w, r, http.StatusNotFound, err)
+			return
+		}
+		HandleError(w, r, http.StatusInternalServerError, fmt.Errorf("error get related comics: %w", err))
+		return
+	}
+	if versionErr == nil {
+		setValidators(w, r, version)
+	}
+	writeJSON(w, related)
+}
+
+func (h *ComicsHandler) GetRandom(w http.ResponseWriter, r *http.Request) {
+	c, err := h.discover.Random(r.Context(), r.URL.Query().Get("keywords"))
+	if err != nil {
+		if errors.Is(err, ports.ErrIsNotExist) {
+			HandleError(w, r, http.StatusNotFound, err)
+			return
+		}
+		HandleError(w, r, http.StatusInternalServerError, fmt.Errorf("error get random comics: %w", err))
+		return
+	}
+	w.Header().Set(cacheControlHeader, "no-store")
+	writeJSON(w, c)
+}
+
+func (h *ComicsHandler) GetToday(w http.ResponseWriter, r *http.Request) {
+	daily, err := h.discover.Today(r.Context())
+	if err != nil {
+		if errors.Is(err, ports.ErrIsNotExist) {
+			HandleError(w, r, http.StatusNotFound, err)
+			return
+		}
+		HandleError(w, r, http.StatusInternalServerError, fmt.Errorf("error get comics of the day: %w", err))
+		return
+	}
+	// the choice holds until the next UTC day
+	now := time.Now().UTC()
+	left := now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+	w.Header().Set(cacheControlHeader, fmt.Sprintf("private, max-age=%d", int(left.Seconds())))
+	writeJSON(w, daily)
 }
 
 func (h *ComicsHandler) UpdateComics(w http.ResponseWriter, r *http.Request) {
+	event := domain.AuditEvent{
+		Actor:  emailFromContext(r.Context()),
+		IP:     clientIP(r),
+		Action: domain.AuditActionUpdate,
+	}
+	ctx, cancel, err := h.election.Lead(r.Context())
+	if err != nil {
+		event.Outcome, event.Details = domain.AuditOutcomeDenied, err.Error()
+		h.audit.Record(r.Context(), event)
+		notLeader(w, r, h.election, err)
+		return
+	}
+	defer cancel()
 	if !h.mutex.TryLock() {
-		HandleError(w, http.StatusAccepted, errAccepted)
+		event.Outcome, event.Details = domain.AuditOutcomeDenied, errAccepted.Error()
+		h.audit.Record(r.Context(), event)
+		HandleError(w, r, http.StatusAccepted, errAccepted)
 		return
 	}
 	defer h.mutex.Unlock()
-	meta, err := h.svc.UpdateComics(r.Context())
+	meta, err := h.svc.UpdateComics(ctx)
+	if err != nil {
+		event.Outcome, event.Details = domain.AuditOutcomeFailure, err.Error()
+	} else {
+		event.Outcome, event.Details = domain.AuditOutcomeSuccess, fmt.Sprintf("new: %d, total: %d", meta.New, meta.Total)
+	}
+	h.audit.Record(r.Context(), event)
 	if err != nil {
 		if errors.Is(err, services.ErrContextDone) {
-			HandleError(w, http.StatusServiceUnavailable, err)
+			HandleError(w, r, http.StatusServiceUnavailable, err)
 			return
 		}
-		HandleError(w, http.StatusInternalServerError, fmt.Errorf("error update comics: %w", err))
+		HandleError(w, r, http.StatusInternalServerError, fmt.Errorf("error update comics: %w", err))
+		return
+	}
+	writeJSON(w, meta)
+}
+
+// notLeader tells the client which instance runs updates, so it can retry
+// there.
+func notLeader(w http.ResponseWriter, r *http.Request, election *services.ElectionService, err error) {
+	leader, lerr := election.Leader(r.Context())
+	if lerr != nil {
+		if !errors.Is(lerr, ports.ErrIsNotExist) {
+			slog.ErrorContext(r.Context(), "error get update leader", "error", lerr)
+		}
+		HandleError(w, r, http.StatusConflict, err)
 		return
 	}
-	json.NewEncoder(w).Encode(meta)
+	HandleErrorWithDetails(w, r, http.StatusConflict, err, map[string]interface{}{
+		"leader":     leader.Holder,
+		"expires_at": leader.ExpiresAt,
+	})
 }
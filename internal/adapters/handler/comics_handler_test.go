@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+	"yadro-project/internal/core/services"
+)
+
+// heldLease is held by another instance.
+type heldLease struct {
+	holder string
+}
+
+func (l heldLease) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	return holder == l.holder, nil
+}
+
+func (l heldLease) Get(ctx context.Context, name string) (domain.Lease, error) {
+	return domain.Lease{Name: name, Holder: l.holder}, nil
+}
+
+func (l heldLease) Release(ctx context.Context, name, holder string) error {
+	return nil
+}
+
+type auditEvents struct {
+	events []domain.AuditEvent
+}
+
+func (a *auditEvents) Append(ctx context.Context, event domain.AuditEvent) error {
+	a.events = append(a.events, event)
+	return nil
+}
+
+func (a *auditEvents) Find(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent, error) {
+	return a.events, nil
+}
+
+// oneComicsRepository stores a single comics, the index has one generation.
+type oneComicsRepository struct {
+	ports.ComicsRepository
+	ports.Indexer
+}
+
+func (oneComicsRepository) GetComics(ctx context.Context) ([]domain.Comics, error) {
+	return []domain.Comics{{ID: 1, Keywords: []string{"python"}}}, nil
+}
+
+func (oneComicsRepository) GetLastUpdateTime(ctx context.Context) (time.Time, error) {
+	return time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), nil
+}
+
+func (oneComicsRepository) GetGeneration(ctx context.Context) (int, error) {
+	return 1, nil
+}
+
+func TestComicsHandler_UpdateOnFollower(t *testing.T) {
+	audit := &auditEvents{}
+	election := services.NewElectionService(heldLease{holder: "replica-2"}, "replica-1", time.Minute)
+	election.Campaign(context.Background())
+	h := NewComicsHandler(nil, nil, nil, services.NewAuditService(audit), election, &sync.Mutex{})
+
+	rec := httptest.NewRecorder()
+	h.UpdateComics(rec, httptest.NewRequest(http.MethodPost, "/api/v1/update", nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("got status %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body.String())
+	}
+	var body struct {
+		Details struct {
+			Leader string `json:"leader"`
+		} `json:"details"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Details.Leader != "replica-2" {
+		t.Errorf("got leader %q, %v, want replica-2", body.Details.Leader, err)
+	}
+	if len(audit.events) != 1 || audit.events[0].Outcome != domain.AuditOutcomeDenied {
+		t.Errorf("got audit events %+v, want one denied update", audit.events)
+	}
+}
+
+func TestComicsHandler_ValidatorsOnlyWithResult(t *testing.T) {
+	repo := oneComicsRepository{}
+	cSVC := services.NewComicsService(repo, nil, repo, nil, 0, 0, 0, nil)
+	h := NewComicsHandler(cSVC, services.NewRelatedService(repo, cSVC), nil, nil, nil, &sync.Mutex{})
+
+	tests := []struct {
+		id     string
+		status int
+		etag   bool
+	}{
+		{"1", http.StatusOK, true},
+		{"999", http.StatusNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/comics/"+tt.id+"/related", nil)
+			req.SetPathValue("id", tt.id)
+			rec := httptest.NewRecorder()
+			h.GetRelated(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if got := rec.Header().Get(etagHeader) != "" && rec.Header().Get(cacheControlHeader) != ""; got != tt.etag {
+				t.Errorf("got ETag %q and Cache-Control %q, want validators %t",
+					rec.Header().Get(etagHeader), rec.Header().Get(cacheControlHeader), tt.etag)
+			}
+		})
+	}
+}
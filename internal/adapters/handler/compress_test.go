@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestCompress(t *testing.T) {
+	body := bytes.Repeat([]byte("xkcd "), minCompressSize)
+	h := compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+
+	tests := []struct {
+		accept   string
+		encoding string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=0, identity", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(acceptEncodingHeader, tt.accept)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get(contentEncodingHeader); got != tt.encoding {
+				t.Fatalf("got Content-Encoding %q, want %q", got, tt.encoding)
+			}
+			var r io.Reader = rec.Body
+			switch tt.encoding {
+			case "gzip":
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("error read gzip: %s", err)
+				}
+				r = zr
+			case "br":
+				r = brotli.NewReader(rec.Body)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("error decode body: %s", err)
+			}
+			if !bytes.Equal(got, body) {
+				t.Errorf("decoded body differs from the written one")
+			}
+		})
+	}
+}
+
+func TestCompress_SmallBody(t *testing.T) {
+	h := compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(acceptEncodingHeader, "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got := rec.Header().Get(contentEncodingHeader); got != "" || rec.Body.String() != "{}" {
+		t.Errorf("got Content-Encoding %q and body %q, want the body as is", got, rec.Body.String())
+	}
+}
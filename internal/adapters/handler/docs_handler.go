@@ -0,0 +1,40 @@
+package handler
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+var (
+	//go:embed docs/openapi.json
+	openAPISpec []byte
+	//go:embed docs/index.html
+	docsPage []byte
+	//go:embed docs/redoc.standalone.js
+	redocScript []byte
+)
+
+type DocsHandler struct{}
+
+func NewDocsHandler() *DocsHandler {
+	return &DocsHandler{}
+}
+
+func (h *DocsHandler) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(contentTypeHeader, "application/json")
+	w.Write(openAPISpec)
+}
+
+func (h *DocsHandler) GetDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(contentTypeHeader, "text/html; charset=utf-8")
+	w.Write(docsPage)
+}
+
+func (h *DocsHandler) GetRedoc(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(contentTypeHeader, "text/javascript; charset=utf-8")
+	w.Write(redocScript)
+}
+
+func OpenAPISpec() []byte {
+	return openAPISpec
+}
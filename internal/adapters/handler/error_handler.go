@@ -1,11 +1,118 @@
 package handler
 
 import (
-	"fmt"
+	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"yadro-project/internal/core/ports"
+	"yadro-project/internal/core/services"
+	"yadro-project/internal/logging"
 )
 
-func HandleError(w http.ResponseWriter, statusCode int, err error) {
+const ( // headers
+	contentTypeHeader = "Content-Type"
+	requestIDHeader   = "X-Request-ID"
+)
+
+const ( // error codes
+	CodeBadRequest         = "bad_request"
+	CodeUnauthorized       = "unauthorized"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeTokenInvalid       = "token_invalid"
+	CodeCSRFInvalid        = "csrf_invalid"
+	CodeForbidden          = "forbidden"
+	CodeNotFound           = "not_found"
+	CodeRateLimited        = "rate_limited"
+	CodeLoginLocked        = "login_locked"
+	CodeLoginThrottled     = "login_throttled"
+	CodeUpdateInProgress   = "update_in_progress"
+	CodeNotLeader          = "not_leader"
+	CodeOverloaded         = "overloaded"
+	CodeUnavailable        = "unavailable"
+	CodeInternal           = "internal"
+)
+
+type ErrorResponse struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	RequestID string      `json:"request_id,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+// errorCodes maps errors safe to show to clients to their codes. The message
+// of a matched error is the message of its sentinel, so wrapped internals
+// never reach the client.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{services.ErrBadCredentials, CodeInvalidCredentials},
+	{services.ErrTokenInvalid, CodeTokenInvalid},
+	{services.ErrForbidden, CodeForbidden},
+	{services.ErrManyRequests, CodeRateLimited},
+	{services.ErrLoginLocked, CodeLoginLocked},
+	{services.ErrLoginThrottled, CodeLoginThrottled},
+	{services.ErrQueueFull, CodeOverloaded},
+	{services.ErrAdmissionTimeout, CodeOverloaded},
+	{services.ErrContextDone, CodeUnavailable},
+	{ports.ErrIsNotExist, CodeNotFound},
+	{errAuthorizationHeaderIsEmpty, CodeUnauthorized},
+	{errHeaderIsNotRequiredMask, CodeUnauthorized},
+	{errUserIsNotExist, CodeUnauthorized},
+	{errCSRFTokenInvalid, CodeCSRFInvalid},
+	{errAccepted, CodeUpdateInProgress},
+	{services.ErrNotLeader, CodeNotLeader},
+}
+
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          CodeBadRequest,
+	http.StatusUnauthorized:        CodeUnauthorized,
+	http.StatusForbidden:           CodeForbidden,
+	http.StatusNotFound:            CodeNotFound,
+	http.StatusTooManyRequests:     CodeRateLimited,
+	http.StatusServiceUnavailable:  CodeUnavailable,
+	http.StatusInternalServerError: CodeInternal,
+}
+
+func HandleError(w http.ResponseWriter, r *http.Request, statusCode int, err error) {
+	HandleErrorWithDetails(w, r, statusCode, err, nil)
+}
+
+func HandleErrorWithDetails(w http.ResponseWriter, r *http.Request, statusCode int, err error, details interface{}) {
+	resp := ErrorResponse{
+		Code:      CodeInternal,
+		Message:   err.Error(),
+		RequestID: logging.RequestID(r.Context()),
+		Details:   details,
+	}
+	known := false
+	// a known error behind 500 is still an internal failure, e.g. a missing
+	// row the service expected to exist
+	if statusCode != http.StatusInternalServerError {
+		for _, c := range errorCodes {
+			if errors.Is(err, c.err) {
+				resp.Code, resp.Message, known = c.code, c.err.Error(), true
+				break
+			}
+		}
+	}
+	if !known {
+		if code, ok := statusCodes[statusCode]; ok {
+			resp.Code = code
+		}
+		if statusCode >= http.StatusInternalServerError {
+			slog.ErrorContext(r.Context(), "request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+			resp.Message, resp.Details = http.StatusText(statusCode), nil
+		}
+	}
+
+	w.Header().Set(contentTypeHeader, "application/json")
 	w.WriteHeader(statusCode)
-	w.Write([]byte(fmt.Sprintf("error: %s", err)))
+	json.NewEncoder(w).Encode(resp)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set(contentTypeHeader, "application/json")
+	json.NewEncoder(w).Encode(v)
 }
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/services"
+)
+
+const (
+	readyTimeout = time.Second * 2
+)
+
+type HealthHandler struct {
+	svc *services.HealthService
+}
+
+func NewHealthHandler(svc *services.HealthService) *HealthHandler {
+	return &HealthHandler{
+		svc: svc,
+	}
+}
+
+// Healthz reports that the process is alive. It doesn't touch dependencies.
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{"status": domain.HealthStatusOK})
+}
+
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	ready := h.svc.Ready(ctx)
+	w.Header().Set(contentTypeHeader, "application/json")
+	if ready.Status != domain.HealthStatusReady {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(ready)
+}
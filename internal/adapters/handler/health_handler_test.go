@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+	"yadro-project/internal/core/services"
+)
+
+func TestHealthHandler_ReadyWithStaleIndex(t *testing.T) {
+	// the comics were updated after the index
+	cSVC := services.NewComicsService(oneComicsRepository{}, nil, noIndex{}, nil, 0, 0, 0, nil)
+	h := NewHealthHandler(services.NewHealthService(map[string]ports.Pinger{"index": noIndex{}}, cSVC))
+
+	rec := httptest.NewRecorder()
+	h.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var ready domain.Readiness
+	if err := json.NewDecoder(rec.Body).Decode(&ready); err != nil {
+		t.Fatal(err)
+	}
+	if ready.Status != domain.HealthStatusReady || !ready.Degraded {
+		t.Errorf("got status %q degraded %t, want ready and degraded", ready.Status, ready.Degraded)
+	}
+	stale := false
+	for _, check := range ready.Checks {
+		stale = stale || check.Name == "index_fresh" && check.Status == domain.HealthStatusStale
+	}
+	if !stale {
+		t.Errorf("got checks %+v, want index_fresh %q", ready.Checks, domain.HealthStatusStale)
+	}
+}
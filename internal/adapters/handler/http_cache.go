@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+	"yadro-project/internal/core/domain"
+)
+
+const ( // caching headers
+	etagHeader            = "ETag"
+	lastModifiedHeader    = "Last-Modified"
+	cacheControlHeader    = "Cache-Control"
+	ifNoneMatchHeader     = "If-None-Match"
+	ifModifiedSinceHeader = "If-Modified-Since"
+)
+
+// setValidators sets the validators of a response computed from the version
+// and the query of the request. Only responses with the result get them, an
+// error is not what a later request revalidates.
+func setValidators(w http.ResponseWriter, r *http.Request, version domain.ComicsVersion) {
+	w.Header().Set(etagHeader, versionETag(version, r))
+	w.Header().Set(lastModifiedHeader, lastModified(version).Format(http.TimeFormat))
+	// results may differ after an update, clients have to revalidate
+	w.Header().Set(cacheControlHeader, "private, no-cache")
+}
+
+// notModified answers 304 with the validators and returns true if the client
+// already has the response for the version.
+func notModified(w http.ResponseWriter, r *http.Request, version domain.ComicsVersion) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	// If-Modified-Since is ignored when If-None-Match is sent, RFC 9110 13.1.3
+	if inm := r.Header.Get(ifNoneMatchHeader); inm != "" {
+		if !etagMatches(inm, versionETag(version, r)) {
+			return false
+		}
+	} else {
+		since, err := http.ParseTime(r.Header.Get(ifModifiedSinceHeader))
+		if err != nil || lastModified(version).After(since) {
+			return false
+		}
+	}
+	setValidators(w, r, version)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+func lastModified(version domain.ComicsVersion) time.Time {
+	return version.UpdatedAt.UTC().Truncate(time.Second)
+}
+
+// versionETag is weak, the body of the same result differs by encoding.
+func versionETag(version domain.ComicsVersion, r *http.Request) string {
+	h := fnv.New64a()
+	h.Write([]byte(r.URL.Path))
+	h.Write([]byte{'?'})
+	h.Write([]byte(r.URL.Query().Encode()))
+	return fmt.Sprintf(`W/"%d-%x-%x"`, version.Generation, version.UpdatedAt.UnixNano(), h.Sum64())
+}
+
+// etagMatches compares the If-None-Match list with the etag weakly.
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"yadro-project/internal/core/domain"
+)
+
+func TestNotModified(t *testing.T) {
+	version := domain.ComicsVersion{Generation: 2, UpdatedAt: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+	rec := httptest.NewRecorder()
+	setValidators(rec, httptest.NewRequest(http.MethodGet, "/api/v1/pics?search=tree", nil), version)
+	etag, modified := rec.Header().Get(etagHeader), rec.Header().Get(lastModifiedHeader)
+	if etag == "" || modified != "Mon, 19 Oct 2026 12:00:00 GMT" {
+		t.Fatalf("got ETag %q and Last-Modified %q", etag, modified)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		header string
+		value  string
+		want   bool
+	}{
+		{"same etag", "/api/v1/pics?search=tree", ifNoneMatchHeader, etag, true},
+		{"etag in list", "/api/v1/pics?search=tree", ifNoneMatchHeader, `W/"other", ` + etag, true},
+		{"any etag", "/api/v1/pics?search=tree", ifNoneMatchHeader, "*", true},
+		{"other etag", "/api/v1/pics?search=tree", ifNoneMatchHeader, `W/"other"`, false},
+		{"etag of another query", "/api/v1/pics?search=python", ifNoneMatchHeader, etag, false},
+		{"not modified since", "/api/v1/pics?search=tree", ifModifiedSinceHeader, modified, true},
+		{"modified since", "/api/v1/pics?search=tree", ifModifiedSinceHeader, "Mon, 01 Jan 2001 00:00:00 GMT", false},
+		{"no validators", "/api/v1/pics?search=tree", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			if got := notModified(rec, req, version); got != tt.want {
+				t.Fatalf("got not modified %t, want %t", got, tt.want)
+			}
+			if tt.want && (rec.Code != http.StatusNotModified || rec.Header().Get(etagHeader) != etag) {
+				t.Errorf("got status %d and ETag %q, want 304 with the validators", rec.Code, rec.Header().Get(etagHeader))
+			}
+			if !tt.want && len(rec.Header()) != 0 {
+				t.Errorf("got headers %v, want none until the result is written", rec.Header())
+			}
+		})
+	}
+}
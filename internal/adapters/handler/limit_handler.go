@@ -1,43 +1,80 @@
 package handler
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"yadro-project/internal/core/domain"
 	"yadro-project/internal/core/services"
+	"yadro-project/internal/metrics"
+)
+
+const ( // headers
+	rateLimitLimitHeader     = "RateLimit-Limit"
+	rateLimitRemainingHeader = "RateLimit-Remaining"
 )
 
 type LimitHandler struct {
-	limitService services.LimitService
-	authService  services.AuthService
+	limitService *services.LimitService
 }
 
-func NewLimitHandler(limitService services.LimitService, authService services.AuthService) *LimitHandler {
+func NewLimitHandler(limitService *services.LimitService) *LimitHandler {
 	return &LimitHandler{
 		limitService: limitService,
-		authService:  authService,
 	}
 }
 
-func (h *LimitHandler) LimitingMiddleware(next http.Handler) http.Handler {
+// RateLimitMiddleware applies the policy of the route. Behind
+// AuthHandler.AuthMiddleware requests are limited per user and role, otherwise
+// they are anonymous and limited per IP.
+func (h *LimitHandler) RateLimitMiddleware(route string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token, err := getToken(r.Header.Get(authorizationHeader))
-		if err != nil {
-			HandleError(w, http.StatusUnauthorized, err)
+		role := roleFromContext(r.Context())
+		p, ok := h.limitService.Policy(route, role)
+		if !ok {
+			next.ServeHTTP(w, r)
 			return
 		}
 
-		email, err := h.authService.GetEmailFromToken(token)
+		subject := emailFromContext(r.Context())
+		if p.Key == services.PolicyKeyIP || subject == "" {
+			subject = clientIP(r)
+		}
+		status, err := h.limitService.Allow(r.Context(), route, role, subject)
+		w.Header().Set(rateLimitLimitHeader, strconv.Itoa(status.Limit))
+		w.Header().Set(rateLimitRemainingHeader, strconv.Itoa(status.Remaining))
 		if err != nil {
-			HandleError(w, http.StatusUnauthorized, err)
+			metrics.RateLimitRejections.WithLabelValues(route).Inc()
+			handleRateLimitError(w, r, status, err)
 			return
 		}
-		reservation, err := h.limitService.Limit(email)
-		if err != nil {
-			HandleError(w, http.StatusTooManyRequests, err)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *LimitHandler) ConcurrencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := h.limitService.Acquire(r.Context()); err != nil {
+			if errors.Is(err, services.ErrOverloaded) {
+				w.Header().Set(retryAfterHeader, strconv.Itoa(int(math.Ceil(h.limitService.RetryAfter().Seconds()))))
+			}
+			HandleError(w, r, http.StatusServiceUnavailable, err)
 			return
 		}
+		defer h.limitService.Release()
 
 		next.ServeHTTP(w, r)
+	})
+}
 
-		h.limitService.Done(reservation)
+func handleRateLimitError(w http.ResponseWriter, r *http.Request, status domain.RateLimitStatus, err error) {
+	retryAfter := int(math.Ceil(status.RetryAfter.Seconds()))
+	w.Header().Set(retryAfterHeader, strconv.Itoa(retryAfter))
+	HandleErrorWithDetails(w, r, http.StatusTooManyRequests, err, map[string]interface{}{
+		"limit":       status.Limit,
+		"remaining":   status.Remaining,
+		"retry_after": retryAfter,
 	})
 }
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"yadro-project/internal/adapters/limiter"
+	"yadro-project/internal/core/services"
+)
+
+func TestLimitHandler_RateLimitMiddleware(t *testing.T) {
+	h := NewLimitHandler(services.NewLimitService([]services.RoutePolicy{
+		{Route: "GET /pics", Key: services.PolicyKeyIP, Limit: 1, Per: time.Minute},
+	}, limiter.NewMemoryLimiter(0, 0), services.NewAdmission(1, 1, time.Second, nil)))
+	next := h.RateLimitMiddleware("GET /pics", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	next.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/pics", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get(rateLimitRemainingHeader) != "0" {
+		t.Fatalf("got status %d and remaining %q, want %d and \"0\"", rec.Code, rec.Header().Get(rateLimitRemainingHeader), http.StatusOK)
+	}
+	rec = httptest.NewRecorder()
+	next.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/pics", nil))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get(retryAfterHeader) == "" {
+		t.Fatalf("got status %d and Retry-After %q, want %d", rec.Code, rec.Header().Get(retryAfterHeader), http.StatusTooManyRequests)
+	}
+	if !strings.Contains(rec.Body.String(), `"code":"rate_limited"`) {
+		t.Fatalf("body %s has no rate_limited code", rec.Body.String())
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+	"yadro-project/internal/logging"
+	"yadro-project/internal/metrics"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	maxRequestIDLen = 128
+)
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rr *responseRecorder) WriteHeader(statusCode int) {
+	if rr.status == 0 {
+		rr.status = statusCode
+	}
+	rr.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	n, err := rr.ResponseWriter.Write(b)
+	rr.bytes += n
+	return n, err
+}
+
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+func (rr *responseRecorder) statusCode() int {
+	if rr.status == 0 {
+		return http.StatusOK
+	}
+	return rr.status
+}
+
+// instrument counts requests to the route and observes their latency.
+func instrument(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w}
+		if req := logging.FromContext(r.Context()); req != nil {
+			req.SetRoute(route)
+		}
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(route)
+		span.SetAttributes(semconv.HTTPRoute(route))
+		next.ServeHTTP(rec, r)
+
+		status := strconv.Itoa(rec.statusCode())
+		metrics.HTTPRequests.WithLabelValues(route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(route, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// accessLog assigns the request an id, or keeps the one sent by the client,
+// and logs the request once it is served.
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			var err error
+			if id, err = newRequestID(); err != nil {
+				slog.ErrorContext(r.Context(), "error generate request id", "error", err)
+			}
+		}
+		ctx, req := logging.WithRequest(r.Context(), id)
+		w.Header().Set(requestIDHeader, id)
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		slog.LogAttrs(ctx, slog.LevelInfo, "access",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", req.Route()),
+			slog.Int("status", rec.statusCode()),
+			slog.Duration("latency", time.Since(start)),
+			slog.Int("bytes", rec.bytes),
+			slog.String("remote_addr", r.RemoteAddr),
+		)
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// fallbackRequestIDs numbers requests if random ids can't be generated.
+var fallbackRequestIDs atomic.Uint64
+
+func newRequestID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x-%d", time.Now().UnixNano(), fallbackRequestIDs.Add(1)), err
+	}
+	return hex.EncodeToString(b), nil
+}
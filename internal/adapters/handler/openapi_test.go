@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"yadro-project/internal/core/services"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+func init() {
+	openapi3filter.RegisterBodyDecoder("text/html", openapi3filter.FileBodyDecoder)
+	openapi3filter.RegisterBodyDecoder("text/javascript", openapi3filter.FileBodyDecoder)
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func loadSpec(t *testing.T) (*openapi3.T, routers.Router) {
+	t.Helper()
+	doc, err := openapi3.NewLoader().LoadFromData(OpenAPISpec())
+	if err != nil {
+		t.Fatalf("error load openapi spec: %s", err)
+	}
+	if err = doc.Validate(context.Background()); err != nil {
+		t.Fatalf("openapi spec is invalid: %s", err)
+	}
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		t.Fatalf("error create openapi router: %s", err)
+	}
+	return doc, router
+}
+
+// serve sends the request to the router and checks the response against the
+// operation of the request in the spec, including the status code.
+func serve(t *testing.T, rt *Router, spec routers.Router, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+
+	req.URL.Scheme, req.URL.Host = "http", "localhost"
+	route, params, err := spec.FindRoute(req)
+	if err != nil {
+		t.Fatalf("%s %s: route is not in spec: %s", req.Method, req.URL.Path, err)
+	}
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: params,
+			Route:      route,
+		},
+		Status:  rec.Code,
+		Header:  rec.Header(),
+		Options: &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	input.SetBodyBytes(rec.Body.Bytes())
+	if err = openapi3filter.ValidateResponse(context.Background(), input); err != nil {
+		t.Fatalf("%s %s: response %d %s does not match spec: %s", req.Method, req.URL.Path, rec.Code, rec.Body.String(), err)
+	}
+	return rec
+}
+
+func login(t *testing.T, rt *Router, spec routers.Router, email string) string {
+	t.Helper()
+	rec := serve(t, rt, spec, httptest.NewRequest(http.MethodPost, "/api/v1/login",
+		strings.NewReader(`{"email":"`+email+`","password":"`+testPassword+`"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login %s: got status %d, want %d", email, rec.Code, http.StatusOK)
+	}
+	token, _, _ := strings.Cut(strings.TrimPrefix(rec.Body.String(), `{"token":"`), `"`)
+	return token
+}
+
+func withToken(req *http.Request, token string) *http.Request {
+	req.Header.Set(authorizationHeader, "Bearer "+token)
+	return req
+}
+
+func TestOpenAPI_RoutesAreDocumented(t *testing.T) {
+	doc, _ := loadSpec(t)
+	rt := newTestRouter(t, nil)
+
+	registered := make(map[string]bool)
+	for _, pattern := range rt.Routes() {
+		registered[pattern] = true
+		method, path, _ := strings.Cut(pattern, " ")
+		item := doc.Paths.Find(path)
+		if item == nil || item.GetOperation(method) == nil {
+			t.Errorf("route %q is not documented", pattern)
+		}
+	}
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			if !registered[method+" "+path] {
+				t.Errorf("documented operation \"%s %s\" is not registered", method, path)
+			}
+		}
+	}
+}
+
+func TestOpenAPI_Contract(t *testing.T) {
+	_, spec := loadSpec(t)
+	rt := newTestRouter(t, []services.RoutePolicy{
+		{Route: "GET /comics/today", Key: services.PolicyKeyUser, Limit: 1, Per: time.Minute},
+	})
+
+	user := login(t, rt, spec, testUser)
+	admin := login(t, rt, spec, testAdmin)
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"login bad json", httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(`{`)), http.StatusBadRequest},
+		{"login bad password", httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(`{"email":"`+testUser+`","password":"wrong"}`)), http.StatusUnauthorized},
+		{"logout", httptest.NewRequest(http.MethodPost, "/api/v1/logout", nil), http.StatusNoContent},
+		{"pics without token", httptest.NewRequest(http.MethodGet, "/api/v1/pics?search=tree", nil), http.StatusUnauthorized},
+		{"pics with bad token", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/pics?search=tree", nil), "bad"), http.StatusUnauthorized},
+		{"ready", httptest.NewRequest(http.MethodGet, "/readyz", nil), http.StatusOK},
+		{"update", withToken(httptest.NewRequest(http.MethodPost, "/api/v1/update", nil), user), http.StatusOK},
+		{"pics", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/pics?search=python+trees", nil), user), http.StatusOK},
+		{"random", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/comics/random", nil), user), http.StatusOK},
+		{"random with keywords", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/comics/random?keywords=trees", nil), user), http.StatusOK},
+		{"random without match", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/comics/random?keywords=submarine", nil), user), http.StatusNotFound},
+		{"today", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/comics/today", nil), user), http.StatusOK},
+		{"today rate limited", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/comics/today", nil), user), http.StatusTooManyRequests},
+		{"related", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/comics/1/related?limit=5", nil), user), http.StatusOK},
+		{"related of unknown comics", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/comics/999/related", nil), user), http.StatusNotFound},
+		{"related with bad limit", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/comics/1/related?limit=100", nil), user), http.StatusBadRequest},
+		{"pics without search", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/pics", nil), user), http.StatusBadRequest},
+		{"audit as user", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/admin/audit", nil), user), http.StatusForbidden},
+		{"audit as admin", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/admin/audit?outcome=success&limit=10", nil), admin), http.StatusOK},
+		{"updates as user", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/admin/updates", nil), user), http.StatusForbidden},
+		{"updates as admin", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/admin/updates?limit=5", nil), admin), http.StatusOK},
+		{"consistency as user", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/admin/consistency", nil), user), http.StatusForbidden},
+		{"consistency as admin", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/admin/consistency", nil), admin), http.StatusOK},
+		{"repair as admin", withToken(httptest.NewRequest(http.MethodPost, "/api/v1/admin/consistency/repair", nil), admin), http.StatusOK},
+		{"reindex as user", withToken(httptest.NewRequest(http.MethodPost, "/api/v1/admin/reindex", nil), user), http.StatusForbidden},
+		{"reindex as admin", withToken(httptest.NewRequest(http.MethodPost, "/api/v1/admin/reindex", nil), admin), http.StatusOK},
+		{"audit with bad limit", withToken(httptest.NewRequest(http.MethodGet, "/api/v1/admin/audit?limit=ten", nil), admin), http.StatusBadRequest},
+		{"healthz", httptest.NewRequest(http.MethodGet, "/healthz", nil), http.StatusOK},
+		{"metrics", httptest.NewRequest(http.MethodGet, "/metrics", nil), http.StatusOK},
+		{"openapi", httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil), http.StatusOK},
+		{"docs", httptest.NewRequest(http.MethodGet, "/api/v1/docs", nil), http.StatusOK},
+		{"docs bundle", httptest.NewRequest(http.MethodGet, "/api/v1/docs/redoc.standalone.js", nil), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, rt, spec, tt.req)
+			if rec.Code != tt.status {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"yadro-project/internal/metrics"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+const ( // headers
+	deprecationHeader = "Deprecation"
+	sunsetHeader      = "Sunset"
+	linkHeader        = "Link"
+)
+
+const (
+	apiV1Prefix = "/api/v1"
+)
+
+// Route is a pattern of http.ServeMux without a version prefix, e.g.
+// "GET /pics", and its handler.
+type Route struct {
+	Pattern string
+	Handler http.Handler
+}
+
+// LegacyOptions describes the unversioned aliases of v1 routes kept for old
+// clients. Zero times omit the corresponding header.
+type LegacyOptions struct {
+	Enabled      bool
+	DeprecatedAt time.Time
+	Sunset       time.Time
+}
+
+type Router struct {
+	mux     *http.ServeMux
+	handler http.Handler
+	routes  []string
+}
+
+func NewRouter(c *ComicsHandler, a *AuthHandler, l *LimitHandler, adm *AdminHandler, d *DocsHandler, h *HealthHandler, legacy LegacyOptions) *Router {
+	rt := &Router{
+		mux: http.NewServeMux(),
+	}
+	rt.handler = otelhttp.NewHandler(accessLog(compress(rt.mux)), "http", otelhttp.WithFilter(traced))
+	v1 := []Route{
+		{"GET /pics", a.AuthMiddleware(l.RateLimitMiddleware("GET /pics", http.HandlerFunc(c.GetComics)))},
+		{"GET /comics/random", a.AuthMiddleware(l.RateLimitMiddleware("GET /comics/random", http.HandlerFunc(c.GetRandom)))},
+		{"GET /comics/today", a.AuthMiddleware(l.RateLimitMiddleware("GET /comics/today", http.HandlerFunc(c.GetToday)))},
+		{"GET /comics/{id}/related", a.AuthMiddleware(l.RateLimitMiddleware("GET /comics/{id}/related", http.HandlerFunc(c.GetRelated)))},
+		{"POST /update", a.AuthMiddleware(l.RateLimitMiddleware("POST /update", l.ConcurrencyMiddleware(http.HandlerFunc(c.UpdateComics))))},
+		{"POST /login", l.RateLimitMiddleware("POST /login", http.HandlerFunc(a.LoginHandler))},
+		{"POST /logout", http.HandlerFunc(a.LogoutHandler)},
+		{"GET /admin/audit", a.AuthMiddleware(a.AdminMiddleware(http.HandlerFunc(adm.GetAudit)))},
+		{"GET /admin/updates", a.AuthMiddleware(a.AdminMiddleware(http.HandlerFunc(adm.GetUpdates)))},
+		{"GET /admin/consistency", a.AuthMiddleware(a.AdminMiddleware(http.HandlerFunc(adm.GetConsistency)))},
+		{"POST /admin/consistency/repair", a.AuthMiddleware(a.AdminMiddleware(http.HandlerFunc(adm.RepairConsistency)))},
+		{"POST /admin/reindex", a.AuthMiddleware(a.AdminMiddleware(http.HandlerFunc(adm.Reindex)))},
+	}
+	docs := []Route{
+		{"GET /openapi.json", http.HandlerFunc(d.GetOpenAPI)},
+		{"GET /docs", http.HandlerFunc(d.GetDocs)},
+		{"GET /docs/redoc.standalone.js", http.HandlerFunc(d.GetRedoc)},
+	}
+	rt.Mount(apiV1Prefix, v1)
+	rt.Mount(apiV1Prefix, docs)
+	if legacy.Enabled {
+		rt.Alias("", apiV1Prefix, v1, legacy)
+		rt.Alias("", apiV1Prefix, docs, legacy)
+	}
+	rt.Mount("", []Route{
+		{"GET /metrics", metrics.Handler()},
+		{"GET /healthz", http.HandlerFunc(h.Healthz)},
+		{"GET /readyz", http.HandlerFunc(h.Readyz)},
+	})
+	return rt
+}
+
+// Mount registers the routes under the prefix.
+func (rt *Router) Mount(prefix string, routes []Route) {
+	for _, r := range routes {
+		pattern := withPrefix(prefix, r.Pattern)
+		rt.mux.Handle(pattern, instrument(pattern, r.Handler))
+		rt.routes = append(rt.routes, pattern)
+	}
+}
+
+// Alias registers deprecated copies of the routes under the prefix. Their
+// responses point to the successor under successorPrefix.
+func (rt *Router) Alias(prefix, successorPrefix string, routes []Route, opts LegacyOptions) {
+	for _, r := range routes {
+		pattern := withPrefix(prefix, r.Pattern)
+		_, path, _ := strings.Cut(withPrefix(successorPrefix, r.Pattern), " ")
+		rt.mux.Handle(pattern, instrument(pattern, deprecated(path, opts, r.Handler)))
+	}
+}
+
+// Routes returns the patterns of all registered routes except aliases.
+func (rt *Router) Routes() []string {
+	return rt.routes
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.handler.ServeHTTP(w, r)
+}
+
+func deprecated(successor string, opts LegacyOptions, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if opts.DeprecatedAt.IsZero() {
+			w.Header().Set(deprecationHeader, "true")
+		} else {
+			w.Header().Set(deprecationHeader, fmt.Sprintf("@%d", opts.DeprecatedAt.Unix()))
+		}
+		if !opts.Sunset.IsZero() {
+			w.Header().Set(sunsetHeader, opts.Sunset.UTC().Format(http.TimeFormat))
+		}
+		w.Header().Set(linkHeader, fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// traced leaves probes and scrapes out of traces, they would only be noise.
+func traced(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/metrics", "/healthz", "/readyz":
+		return false
+	}
+	return true
+}
+
+func withPrefix(prefix, pattern string) string {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		return prefix + pattern
+	}
+	return method + " " + prefix + path
+}
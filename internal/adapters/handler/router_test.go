@@ -0,0 +1,428 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"yadro-project/internal/adapters/limiter"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+	"yadro-project/internal/core/services"
+	"yadro-project/pkg/words"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type memComicsRepository struct {
+	mu         sync.Mutex
+	comics     map[int]domain.Comics
+	updateTime time.Time
+	fullCheck  time.Time
+}
+
+func (r *memComicsRepository) GetComics(ctx context.Context) ([]domain.Comics, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ans := make([]domain.Comics, 0, len(r.comics))
+	for _, c := range r.comics {
+		ans = append(ans, c)
+	}
+	return ans, nil
+}
+
+func (r *memComicsRepository) GetCountComics(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.comics), nil
+}
+
+func (r *memComicsRepository) GetIDMissingComics(ctx context.Context, cntInServer int) ([]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ans := make([]int, 0)
+	for i := 1; i <= cntInServer; i++ {
+		if _, ok := r.comics[i]; !ok {
+			ans = append(ans, i)
+		}
+	}
+	return ans, nil
+}
+
+func (r *memComicsRepository) Commit(ctx context.Context, comics []domain.Comics, full bool) (int, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	added := 0
+	for _, c := range comics {
+		if _, ok := r.comics[c.ID]; ok {
+			continue
+		}
+		r.comics[c.ID] = c
+		added++
+	}
+	now := time.Now()
+	if added > 0 {
+		r.updateTime = now
+	}
+	if full {
+		r.fullCheck = now
+	}
+	return added, added > 0, nil
+}
+
+func (r *memComicsRepository) GetLastFullCheckTime(ctx context.Context) (time.Time, error) {
+	return r.fullCheck, nil
+}
+
+func (r *memComicsRepository) CheckConsistency(ctx context.Context) (domain.ConsistencyReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := domain.ConsistencyReport{
+		IndexFresh:      true,
+		MissingPostings: []int{},
+		OrphanPostings:  []int{},
+		EmptyComics:     []int{},
+		OrphanKeywords:  []string{},
+	}
+	for id, c := range r.comics {
+		if len(c.Keywords) == 0 {
+			report.EmptyComics = append(report.EmptyComics, id)
+		}
+	}
+	return report, nil
+}
+
+func (r *memComicsRepository) RepairIndex(ctx context.Context, comics []domain.Comics) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range comics {
+		if len(c.Keywords) > 0 {
+			r.comics[c.ID] = c
+		}
+	}
+	return nil
+}
+
+func (r *memComicsRepository) GetComicsText(ctx context.Context) ([]domain.Comics, error) {
+	return r.GetComics(ctx)
+}
+
+func (r *memComicsRepository) SaveText(ctx context.Context, comics []domain.Comics) error {
+	return nil
+}
+
+func (r *memComicsRepository) BuildIndex(ctx context.Context, comics []domain.Comics) (int, error) {
+	return 1, nil
+}
+
+func (r *memComicsRepository) SwitchIndex(ctx context.Context, generation int) error {
+	r.updateTime = time.Now()
+	return nil
+}
+
+func (r *memComicsRepository) GetRandomComics(ctx context.Context, keywords []string) (domain.ComicsInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, c := range r.comics {
+		if !slices.ContainsFunc(keywords, func(k string) bool { return !slices.Contains(c.Keywords, k) }) {
+			return domain.ComicsInfo{ID: id, ImgURL: c.ImgURL}, nil
+		}
+	}
+	return domain.ComicsInfo{}, ports.ErrIsNotExist
+}
+
+func (r *memComicsRepository) GetLastUpdateTime(ctx context.Context) (time.Time, error) {
+	return r.updateTime, nil
+}
+
+func (r *memComicsRepository) GetURLComicsByID(ctx context.Context, ID int) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.comics[ID]
+	if !ok {
+		return "", ports.ErrIsNotExist
+	}
+	return c.ImgURL, nil
+}
+
+// noIndex makes the service search in the repository.
+type noIndex struct{}
+
+func (noIndex) GetNumbersOfNMostRelevantComics(ctx context.Context, n int, keywords []string) ([]int, error) {
+	return nil, ports.ErrIsNotExist
+}
+
+func (noIndex) GetLastUpdateTime(ctx context.Context) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (noIndex) GetGeneration(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (noIndex) Ping(ctx context.Context) error {
+	return nil
+}
+
+type staticParser struct {
+	comics []domain.Comics
+}
+
+func (p staticParser) GetCountComicsInServer(ctx context.Context) (int, error) {
+	return len(p.comics), nil
+}
+
+func (p staticParser) PartParse(ctx context.Context, isNotExist []int) ([]domain.Comics, error) {
+	return nil, nil
+}
+
+func (p staticParser) FullParse(ctx context.Context, cntInServer int) ([]domain.Comics, error) {
+	return p.comics, nil
+}
+
+type memAuthRepository struct {
+	users  map[string]string
+	admins map[string]string
+}
+
+func (r memAuthRepository) GetPasswordByEmail(email string) (string, error) {
+	if p, ok := r.users[email]; ok {
+		return p, nil
+	}
+	if p, ok := r.admins[email]; ok {
+		return p, nil
+	}
+	return "", ports.ErrIsNotExist
+}
+
+func (r memAuthRepository) CheckAdminByEmail(email string) (bool, error) {
+	_, ok := r.admins[email]
+	return ok, nil
+}
+
+type memAuditRepository struct {
+	mu     sync.Mutex
+	events []domain.AuditEvent
+}
+
+func (r *memAuditRepository) Append(ctx context.Context, event domain.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event.ID = int64(len(r.events) + 1)
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *memAuditRepository) Find(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]domain.AuditEvent(nil), r.events...), nil
+}
+
+type memUpdateHistory struct {
+	mu   sync.Mutex
+	runs []domain.UpdateRun
+}
+
+func (r *memUpdateHistory) Append(ctx context.Context, run domain.UpdateRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run.ID = int64(len(r.runs) + 1)
+	r.runs = append(r.runs, run)
+	return nil
+}
+
+func (r *memUpdateHistory) Find(ctx context.Context, limit int) ([]domain.UpdateRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]domain.UpdateRun(nil), r.runs[:min(limit, len(r.runs))]...), nil
+}
+
+// memDaily lets any comics of the repository be the comics of the day.
+type memDaily struct {
+	repo *memComicsRepository
+	days map[string]int
+}
+
+func (d *memDaily) Get(ctx context.Context, day time.Time) (domain.DailyComics, error) {
+	id, ok := d.days[day.Format(time.DateOnly)]
+	if !ok {
+		return domain.DailyComics{}, ports.ErrIsNotExist
+	}
+	url, err := d.repo.GetURLComicsByID(ctx, id)
+	if err != nil {
+		return domain.DailyComics{}, err
+	}
+	return domain.DailyComics{Date: day.Format(time.DateOnly), ComicsInfo: domain.ComicsInfo{ID: id, ImgURL: url}}, nil
+}
+
+func (d *memDaily) Candidates(ctx context.Context, day time.Time, days int) ([]int, error) {
+	comics, _ := d.repo.GetComics(ctx)
+	ids := make([]int, 0, len(comics))
+	for _, c := range comics {
+		ids = append(ids, c.ID)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (d *memDaily) Save(ctx context.Context, day time.Time, ID int) (domain.DailyComics, error) {
+	if _, ok := d.days[day.Format(time.DateOnly)]; !ok {
+		d.days[day.Format(time.DateOnly)] = ID
+	}
+	return d.Get(ctx, day)
+}
+
+// memLease is held by holder, if set, forever.
+type memLease struct {
+	holder string
+}
+
+func (l *memLease) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	if l.holder != "" && l.holder != holder {
+		return false, nil
+	}
+	l.holder = holder
+	return true, nil
+}
+
+func (l *memLease) Get(ctx context.Context, name string) (domain.Lease, error) {
+	if l.holder == "" {
+		return domain.Lease{}, ports.ErrIsNotExist
+	}
+	return domain.Lease{Name: name, Holder: l.holder, ExpiresAt: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}, nil
+}
+
+func (l *memLease) Release(ctx context.Context, name, holder string) error {
+	return nil
+}
+
+const (
+	testUser     = "user@test.com"
+	testAdmin    = "admin@test.com"
+	testPassword = "password"
+)
+
+// newTestRouter wires every handler to in-memory repositories, the instance
+// is the update leader.
+func newTestRouter(t *testing.T, policies []services.RoutePolicy) *Router {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("error hash password: %s", err)
+	}
+
+	stemmer := words.NewSnowBallStem()
+	repo := &memComicsRepository{comics: make(map[int]domain.Comics)}
+	parser := staticParser{comics: []domain.Comics{
+		{ID: 1, ImgURL: "https://imgs.xkcd.com/comics/barrel_cropped_(1).jpg", Keywords: []string{"boy", "barrel", "float"}},
+		{ID: 2, ImgURL: "https://imgs.xkcd.com/comics/tree_cropped_(1).jpg", Keywords: []string{"tree", "python"}},
+	}}
+	cSVC := services.NewComicsService(repo, parser, noIndex{}, stemmer, 30*24*time.Hour, 100, time.Minute, nil)
+	aSVC := services.NewAuthService(memAuthRepository{
+		users:  map[string]string{testUser: string(hash)},
+		admins: map[string]string{testAdmin: string(hash)},
+	}, time.Minute)
+	gSVC := services.NewLoginGuardService(services.LoginGuardPolicy{
+		MaxFailuresPerIP:      100,
+		MaxFailuresPerAccount: 100,
+		FreeAttempts:          100,
+		FailureWindow:         time.Hour,
+	}, nil)
+	auSVC := services.NewAuditService(&memAuditRepository{})
+	mutex := &sync.Mutex{}
+	eSVC := services.NewElectionService(&memLease{}, "test", time.Minute)
+	eSVC.Campaign(context.Background())
+	lSVC := services.NewLimitService(policies, limiter.NewMemoryLimiter(0, 0), services.NewAdmission(1, 1, time.Second, nil))
+
+	return NewRouter(
+		NewComicsHandler(cSVC, services.NewRelatedService(repo, cSVC), services.NewDiscoveryService(repo, &memDaily{repo: repo, days: make(map[string]int)}, stemmer, 30), auSVC, eSVC, mutex),
+		NewAuthHandler(aSVC, gSVC, auSVC, SessionOptions{CookieName: "session", CSRFCookieName: "csrf_token"}),
+		NewLimitHandler(lSVC),
+		NewAdminHandler(auSVC, services.NewSchedulerService(cSVC, eSVC, mutex, nil, 0, &memUpdateHistory{runs: []domain.UpdateRun{{
+			ID:          1,
+			ScheduledAt: time.Date(2026, 10, 19, 3, 0, 0, 0, time.UTC),
+			StartedAt:   time.Date(2026, 10, 19, 3, 4, 0, 0, time.UTC),
+			FinishedAt:  time.Date(2026, 10, 19, 3, 5, 0, 0, time.UTC),
+			Outcome:     domain.UpdateRunSuccess,
+			New:         1,
+			Total:       2,
+		}}}, auSVC, nil), cSVC, eSVC, mutex),
+		NewDocsHandler(),
+		NewHealthHandler(services.NewHealthService(map[string]ports.Pinger{"index": noIndex{}}, cSVC)),
+		LegacyOptions{
+			Enabled:      true,
+			DeprecatedAt: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+			Sunset:       time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC),
+		},
+	)
+}
+
+func TestRouter_LegacyAliases(t *testing.T) {
+	_, spec := loadSpec(t)
+	rt := newTestRouter(t, nil)
+	user := login(t, rt, spec, testUser)
+
+	tests := []struct {
+		req       *http.Request
+		successor string
+		body      string
+	}{
+		{withToken(httptest.NewRequest(http.MethodGet, "/pics?search=tree", nil), user), "/api/v1/pics", ""},
+		// the spec was served at the root before the API got a version
+		{httptest.NewRequest(http.MethodGet, "/openapi.json", nil), "/api/v1/openapi.json", string(OpenAPISpec())},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, tt.req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got status %d, want %d: %s", tt.req.URL.Path, rec.Code, http.StatusOK, rec.Body.String())
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s: got body %.100q, want %.100q", tt.req.URL.Path, rec.Body.String(), tt.body)
+		}
+		headers := map[string]string{
+			deprecationHeader: "@1792368000",
+			sunsetHeader:      "Thu, 01 Apr 2027 00:00:00 GMT",
+			linkHeader:        "<" + tt.successor + `>; rel="successor-version"`,
+		}
+		for name, want := range headers {
+			if got := rec.Header().Get(name); got != want {
+				t.Errorf("%s: header %s is %q, want %q", tt.req.URL.Path, name, got, want)
+			}
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, withToken(httptest.NewRequest(http.MethodGet, "/api/v1/pics?search=tree", nil), user))
+	if rec.Header().Get(deprecationHeader) != "" {
+		t.Fatalf("versioned route has Deprecation header %q", rec.Header().Get(deprecationHeader))
+	}
+}
+
+func TestRouter_RequestID(t *testing.T) {
+	rt := newTestRouter(t, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pics", nil)
+	req.Header.Set(requestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+	if got := rec.Header().Get(requestIDHeader); got != "req-42" {
+		t.Errorf("got %s %q, want %q", requestIDHeader, got, "req-42")
+	}
+	if !strings.Contains(rec.Body.String(), `"request_id":"req-42"`) {
+		t.Errorf("error body has no request id: %s", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set(requestIDHeader, "bad\nid")
+	rec = httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+	if got := rec.Header().Get(requestIDHeader); got == "" || got == "bad\nid" {
+		t.Errorf("got %s %q, want a generated one", requestIDHeader, got)
+	}
+}
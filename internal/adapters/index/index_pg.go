@@ -7,10 +7,11 @@ import (
 	"time"
 	"yadro-project/internal/config"
 	"yadro-project/internal/core/ports"
-	"yadro-project/pkg/pair"
 
+	"github.com/exaring/otelpgx"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type PostgresConn struct {
@@ -22,6 +23,7 @@ func NewPostgresConn(ctx context.Context, cfg config.PostgresDBConfig) (*Postgre
 	if err != nil {
 		return nil, fmt.Errorf("error parse database config: %w", err)
 	}
+	pgCFG.ConnConfig.Tracer = otelpgx.NewTracer(otelpgx.WithAttributes(attribute.String("db.pool", "index")))
 
 	pool, err := pgxpool.NewWithConfig(ctx, pgCFG)
 	if err != nil {
@@ -33,92 +35,33 @@ func NewPostgresConn(ctx context.Context, cfg config.PostgresDBConfig) (*Postgre
 	}, nil
 }
 
-const getKeywords = `SELECT keyword FROM keyword`
-const getComics = `SELECT index.comics_id FROM keyword INNER JOIN index ON keyword.id = index.keyword_id WHERE keyword = $1`
+const getRelevantComics = `SELECT index.comics_id FROM index
+INNER JOIN keyword ON keyword.id = index.keyword_id
+WHERE keyword.keyword = ANY($1) AND index.generation = (SELECT index_generation FROM time WHERE id = 1)
+GROUP BY index.comics_id
+ORDER BY COUNT(*) DESC, index.comics_id
+LIMIT $2`
 
 func (pg *PostgresConn) GetNumbersOfNMostRelevantComics(ctx context.Context, n int, keywords []string) ([]int, error) {
-	rows, err := pg.pool.Query(ctx, getKeywords)
+	rows, err := pg.pool.Query(ctx, getRelevantComics, keywords, n)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error query relevant comics: %w", err)
 	}
 	defer rows.Close()
-	k := make(map[string][]int)
 
-	var temp string
+	IDs := make([]int, 0, n)
+	var ID int
 	for rows.Next() {
-		if err := rows.Scan(&temp); err != nil {
+		if err := rows.Scan(&ID); err != nil {
 			return nil, fmt.Errorf("error scan: %w", err)
 		}
+		IDs = append(IDs, ID)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows error: %w", err)
 	}
-
-	for keyword, _ := range k {
-		rows, err = pg.pool.Query(ctx, getComics, keyword)
-		if err != nil {
-			return nil, err
-		}
-
-		IDs := make([]int, 0)
-		var ID int
-
-		for rows.Next() {
-			if err := rows.Scan(&ID); err != nil {
-				return nil, fmt.Errorf("error scan: %w", err)
-			}
-			IDs = append(IDs, ID)
-		}
-
-		rows.Close()
-		k[keyword] = IDs
-	}
-
-	cnt := make(map[int]int)
-	for _, keyword := range keywords {
-		for _, number := range k[keyword] {
-			cnt[number]++
-		}
-	}
-
-	return pair.GetNRelevantFromMap(cnt, n), nil
-}
-
-const insertKeyword = `INSERT INTO keyword(keyword) VALUES ($1) ON CONFLICT (keyword) DO NOTHING`
-const insertKeywordComics = `INSERT INTO index(keyword_id, comics_id) VALUES ((SELECT id FROM keyword WHERE keyword = $1), $2)`
-
-func (pg *PostgresConn) UpdateIndex(ctx context.Context, id int, keywords []string) error {
-	tx, err := pg.pool.Begin(ctx)
-	if err != nil {
-		return fmt.Errorf("error create transaction")
-	}
-	defer tx.Rollback(ctx)
-
-	for _, keyword := range keywords {
-		if _, err := tx.Exec(ctx, insertKeyword, keyword); err != nil {
-			return err
-		}
-		if _, err := tx.Exec(ctx, insertKeywordComics, keyword, id); err != nil {
-			return err
-		}
-	}
-
-	if err = tx.Commit(ctx); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-const updateLastUpdateTime = `UPDATE time SET update_time_index = $1 WHERE id = 1`
-
-func (pg *PostgresConn) Save(ctx context.Context, updateTime time.Time) error {
-	if _, err := pg.pool.Exec(ctx, updateLastUpdateTime, updateTime); err != nil {
-		return fmt.Errorf("error update last update time: %w", err)
-	}
-	pg.pool.Close()
-	return nil
+	return IDs, nil
 }
 
 const getLastUpdateTime = `SELECT update_time_index FROM time WHERE id = 1`
@@ -137,11 +80,21 @@ func (pg *PostgresConn) GetLastUpdateTime(ctx context.Context) (time.Time, error
 	return t, nil
 }
 
-const clear = `TRUNCATE TABLE index`
+const getGeneration = `SELECT index_generation FROM time WHERE id = 1`
+
+func (pg *PostgresConn) GetGeneration(ctx context.Context) (int, error) {
+	row := pg.pool.QueryRow(ctx, getGeneration)
 
-func (pg *PostgresConn) Clear(ctx context.Context) error {
-	if _, err := pg.pool.Exec(ctx, clear); err != nil {
-		return fmt.Errorf("error clear index")
+	var generation int
+	if err := row.Scan(&generation); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ports.ErrIsNotExist
+		}
+		return 0, fmt.Errorf("error get index generation: %w", err)
 	}
-	return nil
+	return generation, nil
+}
+
+func (pg *PostgresConn) Ping(ctx context.Context) error {
+	return pg.pool.Ping(ctx)
 }
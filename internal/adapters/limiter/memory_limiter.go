@@ -0,0 +1,129 @@
+package limiter
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+
+	"golang.org/x/time/rate"
+)
+
+type entry struct {
+	key      string
+	rule     domain.RateLimitRule
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// MemoryLimiter keeps a token bucket per key. Buckets are kept in LRU order,
+// so idle buckets and buckets over maxEntries are evicted from the back.
+type MemoryLimiter struct {
+	mu          sync.Mutex
+	rl          map[string]*list.Element
+	lru         *list.List
+	idleTimeout time.Duration
+	maxEntries  int
+	now         func() time.Time
+}
+
+func NewMemoryLimiter(idleTimeout time.Duration, maxEntries int) *MemoryLimiter {
+	return &MemoryLimiter{
+		rl:          make(map[string]*list.Element),
+		lru:         list.New(),
+		idleTimeout: idleTimeout,
+		maxEntries:  maxEntries,
+		now:         time.Now,
+	}
+}
+
+func (ml *MemoryLimiter) Take(ctx context.Context, key string, rule domain.RateLimitRule) (domain.RateLimitStatus, error) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	now := ml.now()
+	limiter := ml.limiter(key, rule, now)
+	status := domain.RateLimitStatus{Limit: rule.Limit}
+	if limiter.AllowN(now, 1) {
+		status.Remaining = int(math.Max(0, math.Floor(limiter.TokensAt(now))))
+		return status, nil
+	}
+
+	r := limiter.ReserveN(now, 1)
+	status.RetryAfter = r.DelayFrom(now)
+	r.CancelAt(now)
+	return status, ports.ErrLimitExceeded
+}
+
+// Run evicts idle buckets until ctx is done.
+func (ml *MemoryLimiter) Run(ctx context.Context) {
+	if ml.idleTimeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(ml.idleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ml.Evict()
+		}
+	}
+}
+
+func (ml *MemoryLimiter) Evict() {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	ml.evict(ml.now(), 0)
+}
+
+func (ml *MemoryLimiter) Len() int {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	return ml.lru.Len()
+}
+
+func (ml *MemoryLimiter) limiter(key string, rule domain.RateLimitRule, now time.Time) *rate.Limiter {
+	if el, ok := ml.rl[key]; ok {
+		e := el.Value.(*entry)
+		e.lastSeen = now
+		ml.lru.MoveToFront(el)
+		// the policy was reloaded, keep the tokens but apply the new rule
+		if e.rule != rule {
+			e.limiter.SetLimitAt(now, rate.Limit(rule.Rate()))
+			e.limiter.SetBurstAt(now, rule.Capacity())
+			e.rule = rule
+		}
+		return e.limiter
+	}
+
+	ml.evict(now, 1)
+	e := &entry{
+		key:      key,
+		rule:     rule,
+		limiter:  rate.NewLimiter(rate.Limit(rule.Rate()), rule.Capacity()),
+		lastSeen: now,
+	}
+	ml.rl[key] = ml.lru.PushFront(e)
+	return e.limiter
+}
+
+// evict drops idle buckets and makes room for n new ones. A bucket is idle
+// only once it has refilled, so evicting it doesn't reset the quota.
+func (ml *MemoryLimiter) evict(now time.Time, n int) {
+	for el := ml.lru.Back(); el != nil; el = ml.lru.Back() {
+		e := el.Value.(*entry)
+		idle := ml.idleTimeout > 0 && now.Sub(e.lastSeen) >= ml.idleTimeout &&
+			e.limiter.TokensAt(now) >= float64(e.limiter.Burst())
+		full := ml.maxEntries > 0 && ml.lru.Len()+n > ml.maxEntries
+		if !idle && !full {
+			return
+		}
+		ml.lru.Remove(el)
+		delete(ml.rl, e.key)
+	}
+}
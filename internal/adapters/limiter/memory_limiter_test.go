@@ -0,0 +1,139 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+)
+
+func TestMemoryLimiter_ConcurrentTake(t *testing.T) {
+	ml := NewMemoryLimiter(time.Millisecond, 8)
+	rule := domain.RateLimitRule{Limit: 1000, Per: time.Second, Burst: 10}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				_, _ = ml.Take(context.Background(), fmt.Sprintf("user%d@test.com", (i+j)%16), rule)
+				if j%50 == 0 {
+					ml.Evict()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := ml.Len(); n > 8 {
+		t.Fatalf("limiter has %d entries, want at most 8", n)
+	}
+}
+
+func TestMemoryLimiter_Burst(t *testing.T) {
+	ml := NewMemoryLimiter(0, 0)
+	rule := domain.RateLimitRule{Limit: 1, Per: time.Second, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		status, err := ml.Take(context.Background(), "user@test.com", rule)
+		if err != nil {
+			t.Fatalf("request %d: unexpected error: %s", i, err)
+		}
+		if status.Remaining != 2-i {
+			t.Fatalf("request %d: remaining %d, want %d", i, status.Remaining, 2-i)
+		}
+	}
+	status, err := ml.Take(context.Background(), "user@test.com", rule)
+	if !errors.Is(err, ports.ErrLimitExceeded) {
+		t.Fatalf("got error %v, want %v", err, ports.ErrLimitExceeded)
+	}
+	if status.RetryAfter <= 0 || status.RetryAfter > time.Second {
+		t.Fatalf("retry after %s, want in (0, 1s]", status.RetryAfter)
+	}
+	if _, err := ml.Take(context.Background(), "other@test.com", rule); err != nil {
+		t.Fatalf("other user: unexpected error: %s", err)
+	}
+}
+
+func TestMemoryLimiter_EvictIdle(t *testing.T) {
+	now := time.Now()
+	ml := NewMemoryLimiter(time.Minute, 0)
+	ml.now = func() time.Time { return now }
+	rule := domain.RateLimitRule{Limit: 1, Per: time.Second}
+
+	for _, email := range []string{"a@test.com", "b@test.com"} {
+		if _, err := ml.Take(context.Background(), email, rule); err != nil {
+			t.Fatalf("%s: unexpected error: %s", email, err)
+		}
+	}
+
+	now = now.Add(time.Second * 30)
+	_, _ = ml.Take(context.Background(), "b@test.com", rule)
+
+	now = now.Add(time.Second * 45)
+	ml.Evict()
+	if n := ml.Len(); n != 1 {
+		t.Fatalf("limiter has %d entries after eviction, want 1", n)
+	}
+}
+
+func TestMemoryLimiter_EvictKeepsQuota(t *testing.T) {
+	now := time.Now()
+	ml := NewMemoryLimiter(time.Minute, 0)
+	ml.now = func() time.Time { return now }
+	rule := domain.RateLimitRule{Limit: 1, Per: time.Hour * 24}
+
+	if _, err := ml.Take(context.Background(), "a@test.com", rule); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	now = now.Add(time.Hour)
+	ml.Evict()
+	if _, err := ml.Take(context.Background(), "a@test.com", rule); !errors.Is(err, ports.ErrLimitExceeded) {
+		t.Fatalf("got error %v after eviction, want %v", err, ports.ErrLimitExceeded)
+	}
+}
+
+func TestMemoryLimiter_MaxEntries(t *testing.T) {
+	ml := NewMemoryLimiter(0, 3)
+	rule := domain.RateLimitRule{Limit: 1, Per: time.Second}
+
+	for i := 0; i < 10; i++ {
+		if _, err := ml.Take(context.Background(), fmt.Sprintf("user%d@test.com", i), rule); err != nil {
+			t.Fatalf("user %d: unexpected error: %s", i, err)
+		}
+	}
+	if n := ml.Len(); n != 3 {
+		t.Fatalf("limiter has %d entries, want 3", n)
+	}
+}
+
+func TestMemoryLimiter_RuleChange(t *testing.T) {
+	now := time.Now()
+	ml := NewMemoryLimiter(0, 0)
+	ml.now = func() time.Time { return now }
+	rule := domain.RateLimitRule{Limit: 1, Per: time.Hour}
+
+	if _, err := ml.Take(context.Background(), "a@test.com", rule); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// a reloaded policy applies to the existing bucket, the tokens already
+	// taken keep counting
+	rule = domain.RateLimitRule{Limit: 3600, Per: time.Hour, Burst: 3}
+	if _, err := ml.Take(context.Background(), "a@test.com", rule); !errors.Is(err, ports.ErrLimitExceeded) {
+		t.Fatalf("got error %v, want %v", err, ports.ErrLimitExceeded)
+	}
+	now = now.Add(time.Second * 2)
+	status, err := ml.Take(context.Background(), "a@test.com", rule)
+	if err != nil {
+		t.Fatalf("unexpected error after the rule change: %s", err)
+	}
+	if status.Limit != 3600 || status.Remaining != 1 {
+		t.Fatalf("got limit %d remaining %d, want 3600 and 1", status.Limit, status.Remaining)
+	}
+}
@@ -0,0 +1,97 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+	"yadro-project/internal/config"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+
+	"github.com/exaring/otelpgx"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PostgresLimiter keeps token buckets in the rate_limit table, so every
+// replica of the server shares the same quota. A bucket row is updated by a
+// single upsert, which locks the row and makes concurrent takes atomic.
+type PostgresLimiter struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresLimiter(ctx context.Context, cfg config.PostgresDBConfig) (*PostgresLimiter, error) {
+	pgCFG, err := pgxpool.ParseConfig(cfg.String())
+	if err != nil {
+		return nil, fmt.Errorf("error parse database config: %w", err)
+	}
+	pgCFG.ConnConfig.Tracer = otelpgx.NewTracer(otelpgx.WithAttributes(attribute.String("db.pool", "rate_limit")))
+
+	pool, err := pgxpool.NewWithConfig(ctx, pgCFG)
+	if err != nil {
+		return nil, fmt.Errorf("error create new postgres pool: %w", err)
+	}
+
+	return &PostgresLimiter{
+		pool: pool,
+	}, nil
+}
+
+// $1 - key, $2 - capacity, $3 - tokens per second.
+const takeToken = `
+INSERT INTO rate_limit AS rl (key, tokens, updated_at, full_at)
+VALUES ($1, $2::float8 - 1, now(), now() + make_interval(secs => 1 / $3::float8))
+ON CONFLICT (key) DO UPDATE SET
+    tokens = LEAST($2::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at)::float8 * $3::float8) - 1,
+    updated_at = now(),
+    full_at = now() + make_interval(secs => ($2::float8 - LEAST($2::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at)::float8 * $3::float8) + 1) / $3::float8)
+WHERE LEAST($2::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at)::float8 * $3::float8) >= 1
+RETURNING tokens`
+
+const getTokens = `SELECT LEAST($2::float8, tokens + EXTRACT(EPOCH FROM now() - updated_at)::float8 * $3::float8) FROM rate_limit WHERE key = $1`
+
+func (pg *PostgresLimiter) Take(ctx context.Context, key string, rule domain.RateLimitRule) (domain.RateLimitStatus, error) {
+	capacity, rate := float64(rule.Capacity()), rule.Rate()
+	status := domain.RateLimitStatus{Limit: rule.Limit}
+
+	var tokens float64
+	err := pg.pool.QueryRow(ctx, takeToken, key, capacity, rate).Scan(&tokens)
+	if err == nil {
+		status.Remaining = int(math.Max(0, math.Floor(tokens)))
+		return status, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return domain.RateLimitStatus{}, fmt.Errorf("error take token: %w", err)
+	}
+
+	if err = pg.pool.QueryRow(ctx, getTokens, key, capacity, rate).Scan(&tokens); err != nil {
+		return domain.RateLimitStatus{}, fmt.Errorf("error get tokens: %w", err)
+	}
+	status.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	return status, ports.ErrLimitExceeded
+}
+
+const deleteFullBuckets = `DELETE FROM rate_limit WHERE full_at < now()`
+
+// Run deletes refilled buckets every interval until ctx is done.
+func (pg *PostgresLimiter) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := pg.pool.Exec(ctx, deleteFullBuckets); err != nil {
+				slog.ErrorContext(ctx, "error delete refilled rate limit buckets", "error", err)
+			}
+		}
+	}
+}
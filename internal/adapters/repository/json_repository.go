@@ -71,6 +71,7 @@ func (db *JsonDB) Add(ctx context.Context, comics domain.Comics, id int) error {
 	if _, ok := db.Data.Comics[id]; ok {
 		return errors.New(fmt.Sprintf("Comics with id %d already exist", id))
 	}
+	comics.ID = id
 	db.Data.Comics[id] = comics
 	db.SliceComics = append(db.SliceComics, comics)
 	db.wasChanged = true
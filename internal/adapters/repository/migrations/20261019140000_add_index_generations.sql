@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE comics
+    ADD COLUMN transcript TEXT NOT NULL DEFAULT '',
+    ADD COLUMN alt TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE index
+    ADD COLUMN generation INTEGER NOT NULL DEFAULT 0,
+    DROP CONSTRAINT index_keyword_id_comics_id_key,
+    ADD UNIQUE(generation, keyword_id, comics_id);
+
+ALTER TABLE time ADD COLUMN index_generation INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM index WHERE generation <> (SELECT index_generation FROM time WHERE id = 1);
+ALTER TABLE time DROP COLUMN index_generation;
+
+ALTER TABLE index
+    DROP CONSTRAINT index_generation_keyword_id_comics_id_key,
+    DROP COLUMN generation,
+    ADD UNIQUE(keyword_id, comics_id);
+
+ALTER TABLE comics
+    DROP COLUMN transcript,
+    DROP COLUMN alt;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE daily_comics (
+    day DATE PRIMARY KEY,
+    comics_id INTEGER NOT NULL REFERENCES comics(id) ON UPDATE RESTRICT ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE daily_comics;
+-- +goose StatementEnd
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"yadro-project/internal/config"
+	"yadro-project/internal/core/domain"
+
+	"github.com/exaring/otelpgx"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type AuditPostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditPostgresRepository(ctx context.Context, cfg config.PostgresDBConfig) (*AuditPostgresRepository, error) {
+	pgCFG, err := pgxpool.ParseConfig(cfg.String())
+	if err != nil {
+		return nil, fmt.Errorf("error parse database config: %w", err)
+	}
+	pgCFG.ConnConfig.Tracer = otelpgx.NewTracer(otelpgx.WithAttributes(attribute.String("db.pool", "audit")))
+
+	pool, err := pgxpool.NewWithConfig(ctx, pgCFG)
+	if err != nil {
+		return nil, fmt.Errorf("error create new postgres pool: %w", err)
+	}
+
+	return &AuditPostgresRepository{
+		pool: pool,
+	}, nil
+}
+
+const insertAuditEvent = `INSERT INTO audit_log(time, actor, ip, action, outcome, details) VALUES ($1, $2, $3, $4, $5, $6)`
+
+func (pg *AuditPostgresRepository) Append(ctx context.Context, event domain.AuditEvent) error {
+	if _, err := pg.pool.Exec(ctx, insertAuditEvent, event.Time, event.Actor, event.IP, event.Action, event.Outcome, event.Details); err != nil {
+		return fmt.Errorf("error insert audit event: %w", err)
+	}
+	return nil
+}
+
+const findAuditEvents = `SELECT id, time, actor, ip, action, outcome, details FROM audit_log`
+
+func (pg *AuditPostgresRepository) Find(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent, error) {
+	conds := make([]string, 0)
+	args := make([]interface{}, 0)
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.Actor != "" {
+		add("actor = $%d", filter.Actor)
+	}
+	if filter.IP != "" {
+		add("ip = $%d", filter.IP)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.Outcome != "" {
+		add("outcome = $%d", filter.Outcome)
+	}
+	if !filter.From.IsZero() {
+		add("time >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("time < $%d", filter.To)
+	}
+
+	query := findAuditEvents
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY time DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := pg.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]domain.AuditEvent, 0)
+	for rows.Next() {
+		e := domain.AuditEvent{}
+		if err = rows.Scan(&e.ID, &e.Time, &e.Actor, &e.IP, &e.Action, &e.Outcome, &e.Details); err != nil {
+			return nil, fmt.Errorf("error scan: %w", err)
+		}
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error rows: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"yadro-project/internal/config"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+
+	"github.com/exaring/otelpgx"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type DailyPostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewDailyPostgresRepository(ctx context.Context, cfg config.PostgresDBConfig) (*DailyPostgresRepository, error) {
+	pgCFG, err := pgxpool.ParseConfig(cfg.String())
+	if err != nil {
+		return nil, fmt.Errorf("error parse database config: %w", err)
+	}
+	pgCFG.ConnConfig.Tracer = otelpgx.NewTracer(otelpgx.WithAttributes(attribute.String("db.pool", "daily")))
+
+	pool, err := pgxpool.NewWithConfig(ctx, pgCFG)
+	if err != nil {
+		return nil, fmt.Errorf("error create new postgres pool: %w", err)
+	}
+
+	return &DailyPostgresRepository{
+		pool: pool,
+	}, nil
+}
+
+const getDailyComics = `SELECT comics.id, comics.image_url FROM daily_comics
+INNER JOIN comics ON comics.id = daily_comics.comics_id
+WHERE daily_comics.day = $1::date`
+
+func (pg *DailyPostgresRepository) Get(ctx context.Context, day time.Time) (domain.DailyComics, error) {
+	daily := domain.DailyComics{Date: day.Format(time.DateOnly)}
+	if err := pg.pool.QueryRow(ctx, getDailyComics, daily.Date).Scan(&daily.ID, &daily.ImgURL); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.DailyComics{}, ports.ErrIsNotExist
+		}
+		return domain.DailyComics{}, fmt.Errorf("error get comics of the day: %w", err)
+	}
+	return daily, nil
+}
+
+const getDailyCandidates = `SELECT id FROM comics
+WHERE id NOT IN (SELECT comics_id FROM daily_comics WHERE day >= $1::date - $2::int AND day < $1::date)
+ORDER BY id`
+
+func (pg *DailyPostgresRepository) Candidates(ctx context.Context, day time.Time, days int) ([]int, error) {
+	rows, err := pg.pool.Query(ctx, getDailyCandidates, day.Format(time.DateOnly), days)
+	if err != nil {
+		return nil, fmt.Errorf("error query candidates: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fmt.Errorf("error scan candidates: %w", err)
+	}
+	return ids, nil
+}
+
+const insertDailyComics = `INSERT INTO daily_comics(day, comics_id) VALUES ($1::date, $2) ON CONFLICT (day) DO NOTHING`
+
+func (pg *DailyPostgresRepository) Save(ctx context.Context, day time.Time, ID int) (domain.DailyComics, error) {
+	if _, err := pg.pool.Exec(ctx, insertDailyComics, day.Format(time.DateOnly), ID); err != nil {
+		return domain.DailyComics{}, fmt.Errorf("error insert comics of the day: %w", err)
+	}
+	// another instance may have chosen first
+	return pg.Get(ctx, day)
+}
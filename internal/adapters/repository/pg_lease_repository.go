@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"yadro-project/internal/config"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+
+	"github.com/exaring/otelpgx"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// LeasePostgresRepository keeps leases in a table. Expiry is checked against
+// the database clock, so instances don't have to agree on time.
+type LeasePostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewLeasePostgresRepository(ctx context.Context, cfg config.PostgresDBConfig) (*LeasePostgresRepository, error) {
+	pgCFG, err := pgxpool.ParseConfig(cfg.String())
+	if err != nil {
+		return nil, fmt.Errorf("error parse database config: %w", err)
+	}
+	pgCFG.ConnConfig.Tracer = otelpgx.NewTracer(otelpgx.WithAttributes(attribute.String("db.pool", "lease")))
+
+	pool, err := pgxpool.NewWithConfig(ctx, pgCFG)
+	if err != nil {
+		return nil, fmt.Errorf("error create new postgres pool: %w", err)
+	}
+
+	return &LeasePostgresRepository{
+		pool: pool,
+	}, nil
+}
+
+const acquireLease = `
+INSERT INTO leases(name, holder, expires_at) VALUES ($1, $2, now() + make_interval(secs => $3::float8))
+ON CONFLICT (name) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+WHERE leases.holder = EXCLUDED.holder OR leases.expires_at < now()
+RETURNING holder`
+
+func (pg *LeasePostgresRepository) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	var got string
+	err := pg.pool.QueryRow(ctx, acquireLease, name, holder, ttl.Seconds()).Scan(&got)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error acquire lease %q: %w", name, err)
+	}
+	return true, nil
+}
+
+const getLease = `SELECT name, holder, expires_at FROM leases WHERE name = $1 AND expires_at >= now()`
+
+func (pg *LeasePostgresRepository) Get(ctx context.Context, name string) (domain.Lease, error) {
+	l := domain.Lease{}
+	err := pg.pool.QueryRow(ctx, getLease, name).Scan(&l.Name, &l.Holder, &l.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Lease{}, ports.ErrIsNotExist
+	}
+	if err != nil {
+		return domain.Lease{}, fmt.Errorf("error get lease %q: %w", name, err)
+	}
+	return l, nil
+}
+
+const releaseLease = `DELETE FROM leases WHERE name = $1 AND holder = $2`
+
+func (pg *LeasePostgresRepository) Release(ctx context.Context, name, holder string) error {
+	if _, err := pg.pool.Exec(ctx, releaseLease, name, holder); err != nil {
+		return fmt.Errorf("error release lease %q: %w", name, err)
+	}
+	return nil
+}
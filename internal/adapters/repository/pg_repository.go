@@ -10,8 +10,10 @@ import (
 	"yadro-project/internal/core/domain"
 	"yadro-project/internal/core/ports"
 
+	"github.com/exaring/otelpgx"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type PostgresConn struct {
@@ -23,6 +25,7 @@ func NewPostgresConn(ctx context.Context, cfg config.PostgresDBConfig) (*Postgre
 	if err != nil {
 		return nil, fmt.Errorf("error parse database config: %w", err)
 	}
+	pgCFG.ConnConfig.Tracer = otelpgx.NewTracer(otelpgx.WithAttributes(attribute.String("db.pool", "comics")))
 
 	pool, err := pgxpool.NewWithConfig(ctx, pgCFG)
 	if err != nil {
@@ -38,13 +41,18 @@ func NewPostgresConn(ctx context.Context, cfg config.PostgresDBConfig) (*Postgre
 	}, nil
 }
 
-const getComics = `SELECT * FROM comics`
+const getComics = `SELECT comics.id, comics.image_url, COALESCE(array_agg(keyword.keyword) FILTER (WHERE keyword.keyword IS NOT NULL), '{}')
+FROM comics
+LEFT JOIN comics_keyword ON comics_keyword.comics_id = comics.id
+LEFT JOIN keyword ON keyword.id = comics_keyword.keyword_id
+GROUP BY comics.id`
 
 func (pg *PostgresConn) GetComics(ctx context.Context) ([]domain.Comics, error) {
 	rows, err := pg.pool.Query(ctx, getComics)
 	if err != nil {
 		return nil, fmt.Errorf("error get query of comics: %w", err)
 	}
+	defer rows.Close()
 
 	comics := make([]domain.Comics, 0)
 	for rows.Next() {
@@ -58,42 +66,9 @@ func (pg *PostgresConn) GetComics(ctx context.Context) ([]domain.Comics, error)
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error rows: %w", err)
 	}
-
-	for idx, c := range comics {
-		keywords, err := pg.getKeywordById(ctx, c.ID)
-		if err != nil {
-			return nil, fmt.Errorf("eror get keywords by id: %w", err)
-		}
-		comics[idx].Keywords = keywords
-	}
 	return comics, nil
 }
 
-const getKeywordById = `SELECT keyword FROM keyword INNER JOIN comics_keyword ON keyword.id = comics_keyword.keyword_id WHERE comics_keyword.comics_id = $1`
-
-func (pg *PostgresConn) getKeywordById(ctx context.Context, id int) ([]string, error) {
-	rows, err := pg.pool.Query(ctx, getKeywordById, id)
-	if err != nil {
-		return nil, fmt.Errorf("error query: %w", err)
-	}
-
-	defer rows.Close()
-
-	ans := make([]string, 0)
-	var temp string
-	for rows.Next() {
-		if err = rows.Scan(&temp); err != nil {
-			return nil, fmt.Errorf("error scan: %w", err)
-		}
-		ans = append(ans, temp)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error rows: %w", err)
-	}
-	return ans, nil
-}
-
 const getCountComics = `SELECT COUNT(*) FROM comics`
 
 func (pg *PostgresConn) GetCountComics(ctx context.Context) (int, error) {
@@ -132,9 +107,9 @@ func (pg *PostgresConn) GetIDMissingComics(ctx context.Context, cntInServer int)
 		return nil, fmt.Errorf("error rows: %w", err)
 	}
 
-	missingIDs := make([]int, 0, cntInServer-len(ids))
+	missingIDs := make([]int, 0, max(cntInServer-len(ids), 0))
 
-	for i := 1; i < cntInServer; i++ {
+	for i := 1; i <= cntInServer; i++ {
 		if !ids[i] {
 			missingIDs = append(missingIDs, i)
 		}
@@ -143,69 +118,335 @@ func (pg *PostgresConn) GetIDMissingComics(ctx context.Context, cntInServer int)
 	return missingIDs, nil
 }
 
-const comicsIsExist = `SELECT 1 FROM comics WHERE id = $1`
+const (
+	lockTime            = `SELECT update_time_comics, update_time_index FROM time WHERE id = 1 FOR UPDATE`
+	insertComics        = `INSERT INTO comics(id, image_url, transcript, alt) VALUES ($1, $2, $3, $4) ON CONFLICT (id) DO NOTHING`
+	insertKeywords      = `INSERT INTO keyword(keyword) SELECT unnest($1::text[]) ON CONFLICT (keyword) DO NOTHING`
+	insertComicsKeyword = `INSERT INTO comics_keyword(comics_id, keyword_id) SELECT $1, id FROM keyword WHERE keyword = ANY($2) ON CONFLICT DO NOTHING`
+	clearIndex          = `DELETE FROM index`
+	rebuildIndex        = `INSERT INTO index(keyword_id, comics_id, generation) SELECT keyword_id, comics_id, (SELECT index_generation FROM time WHERE id = 1) FROM comics_keyword`
+	extendIndex         = `INSERT INTO index(keyword_id, comics_id, generation) SELECT keyword_id, comics_id, (SELECT index_generation FROM time WHERE id = 1) FROM comics_keyword WHERE comics_id = ANY($1) ON CONFLICT DO NOTHING`
+	updateTimes         = `UPDATE time SET update_time_comics = $1, update_time_index = $1 WHERE id = 1`
+)
 
-func (pg *PostgresConn) comicsIsExist(ctx context.Context, id int) (bool, error) {
-	row := pg.pool.QueryRow(ctx, comicsIsExist, id)
+// Commit stores the comics that are not in the repository yet and indexes
+// them in one transaction, so readers see either the previous state or the
+// whole update. A stale index is rebuilt from the stored comics. Both update
+// times are set when the index changes, the full check time too if full is
+// set.
+func (pg *PostgresConn) Commit(ctx context.Context, comics []domain.Comics, full bool) (int, bool, error) {
+	tx, err := pg.pool.Begin(ctx)
+	if err != nil {
+		return 0, false, fmt.Errorf("error create tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
 
-	if err := row.Scan(new(int)); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return false, nil
+	var comicsTime, indexTime time.Time
+	if err = tx.QueryRow(ctx, lockTime).Scan(&comicsTime, &indexTime); err != nil {
+		return 0, false, fmt.Errorf("error lock update time: %w", err)
+	}
+
+	added := make([]int, 0, len(comics))
+	for _, c := range comics {
+		tag, err := tx.Exec(ctx, insertComics, c.ID, c.ImgURL, c.Transcript, c.Alt)
+		if err != nil {
+			return 0, false, fmt.Errorf("error insert comics %d: %w", c.ID, err)
+		}
+		if tag.RowsAffected() == 0 {
+			continue
+		}
+		if _, err = tx.Exec(ctx, insertKeywords, c.Keywords); err != nil {
+			return 0, false, fmt.Errorf("error insert keywords of comics %d: %w", c.ID, err)
 		}
-		return false, fmt.Errorf("error check comics is exist: %w", err)
+		if _, err = tx.Exec(ctx, insertComicsKeyword, c.ID, c.Keywords); err != nil {
+			return 0, false, fmt.Errorf("error link keywords of comics %d: %w", c.ID, err)
+		}
+		added = append(added, c.ID)
 	}
-	return true, nil
+
+	stale := !comicsTime.Equal(indexTime)
+	if stale {
+		if _, err = tx.Exec(ctx, clearIndex); err != nil {
+			return 0, false, fmt.Errorf("error clear index: %w", err)
+		}
+		if _, err = tx.Exec(ctx, rebuildIndex); err != nil {
+			return 0, false, fmt.Errorf("error rebuild index: %w", err)
+		}
+	} else if len(added) > 0 {
+		if _, err = tx.Exec(ctx, extendIndex, added); err != nil {
+			return 0, false, fmt.Errorf("error update index: %w", err)
+		}
+	}
+
+	// the column has no time zone and microsecond precision, keep the value
+	// the readers will compare against
+	updateTime := time.Now().UTC().Truncate(time.Microsecond)
+	if stale || len(added) > 0 {
+		if _, err = tx.Exec(ctx, updateTimes, updateTime); err != nil {
+			return 0, false, fmt.Errorf("error update last update time: %w", err)
+		}
+	}
+	if full {
+		if _, err = tx.Exec(ctx, updateLastFullCheckTime, updateTime); err != nil {
+			return 0, false, fmt.Errorf("error update last full check time: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, false, fmt.Errorf("error commit: %w", err)
+	}
+	return len(added), stale || len(added) > 0, nil
 }
 
 const (
-	insertKeyword       = `INSERT INTO keyword(keyword) VALUES ($1) ON CONFLICT (keyword) DO NOTHING`
-	insertComics        = `INSERT INTO comics VALUES ($1, $2) ON CONFLICT (id) DO NOTHING`
-	insertComicsKeyword = `INSERT INTO comics_keyword(comics_id, keyword_id) VALUES ($1, (SELECT keyword.id FROM keyword WHERE keyword.keyword = $2))`
+	getFreshness    = `SELECT update_time_comics = update_time_index FROM time WHERE id = 1`
+	missingPostings = `SELECT DISTINCT comics_keyword.comics_id FROM comics_keyword
+LEFT JOIN index ON index.comics_id = comics_keyword.comics_id AND index.keyword_id = comics_keyword.keyword_id
+AND index.generation = (SELECT index_generation FROM time WHERE id = 1)
+WHERE index.id IS NULL ORDER BY 1`
+	orphanPostings = `SELECT DISTINCT index.comics_id FROM index
+LEFT JOIN comics_keyword ON comics_keyword.comics_id = index.comics_id AND comics_keyword.keyword_id = index.keyword_id
+WHERE comics_keyword.id IS NULL AND index.generation = (SELECT index_generation FROM time WHERE id = 1) ORDER BY 1`
+	emptyComics    = `SELECT id FROM comics WHERE NOT EXISTS (SELECT 1 FROM comics_keyword WHERE comics_id = comics.id) ORDER BY id`
+	orphanKeywords = `SELECT keyword FROM keyword
+WHERE NOT EXISTS (SELECT 1 FROM comics_keyword WHERE keyword_id = keyword.id)
+AND NOT EXISTS (SELECT 1 FROM index WHERE keyword_id = keyword.id) ORDER BY keyword`
 )
 
-func (pg *PostgresConn) Add(ctx context.Context, comics domain.Comics, id int) error {
-	isExist, err := pg.comicsIsExist(ctx, comics.ID)
+// CheckConsistency compares the index with the stored keywords of the comics
+// in one snapshot.
+func (pg *PostgresConn) CheckConsistency(ctx context.Context) (domain.ConsistencyReport, error) {
+	tx, err := pg.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
 	if err != nil {
-		return err
+		return domain.ConsistencyReport{}, fmt.Errorf("error create tx: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	if isExist {
-		return ports.ErrIsExist
+	report := domain.ConsistencyReport{}
+	if err = tx.QueryRow(ctx, getFreshness).Scan(&report.IndexFresh); err != nil {
+		return domain.ConsistencyReport{}, fmt.Errorf("error get index freshness: %w", err)
+	}
+	if report.MissingPostings, err = collect[int](ctx, tx, missingPostings); err != nil {
+		return domain.ConsistencyReport{}, fmt.Errorf("error find missing postings: %w", err)
+	}
+	if report.OrphanPostings, err = collect[int](ctx, tx, orphanPostings); err != nil {
+		return domain.ConsistencyReport{}, fmt.Errorf("error find orphan postings: %w", err)
+	}
+	if report.EmptyComics, err = collect[int](ctx, tx, emptyComics); err != nil {
+		return domain.ConsistencyReport{}, fmt.Errorf("error find comics without keywords: %w", err)
+	}
+	if report.OrphanKeywords, err = collect[string](ctx, tx, orphanKeywords); err != nil {
+		return domain.ConsistencyReport{}, fmt.Errorf("error find orphan keywords: %w", err)
+	}
+	return report, nil
+}
+
+func collect[T any](ctx context.Context, tx pgx.Tx, query string) ([]T, error) {
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return nil, err
 	}
+	return pgx.CollectRows(rows, pgx.RowTo[T])
+}
 
+const (
+	clearComicsIndex     = `DELETE FROM index WHERE comics_id = ANY($1) AND generation = (SELECT index_generation FROM time WHERE id = 1)`
+	deleteOrphanKeywords = `DELETE FROM keyword
+WHERE NOT EXISTS (SELECT 1 FROM comics_keyword WHERE keyword_id = keyword.id)
+AND NOT EXISTS (SELECT 1 FROM index WHERE keyword_id = keyword.id)`
+)
+
+// RepairIndex rebuilds a stale index whole, otherwise only the postings of
+// the comics. Both update times are set, so the index is fresh afterwards.
+func (pg *PostgresConn) RepairIndex(ctx context.Context, comics []domain.Comics) error {
 	tx, err := pg.pool.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("error create tx: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	if _, err := tx.Exec(ctx, insertComics, comics.ID, comics.ImgURL); err != nil {
-		return fmt.Errorf("error insert comics: %w", err)
+	// wait for a running commit, it may be rebuilding the index
+	var comicsTime, indexTime time.Time
+	if err = tx.QueryRow(ctx, lockTime).Scan(&comicsTime, &indexTime); err != nil {
+		return fmt.Errorf("error lock update time: %w", err)
 	}
 
-	for _, keyword := range comics.Keywords {
-		if _, err := tx.Exec(ctx, insertKeyword, keyword); err != nil {
-			return err
+	ids := make([]int, 0, len(comics))
+	for _, c := range comics {
+		if len(c.Keywords) > 0 {
+			if _, err = tx.Exec(ctx, insertKeywords, c.Keywords); err != nil {
+				return fmt.Errorf("error insert keywords of comics %d: %w", c.ID, err)
+			}
+			if _, err = tx.Exec(ctx, insertComicsKeyword, c.ID, c.Keywords); err != nil {
+				return fmt.Errorf("error link keywords of comics %d: %w", c.ID, err)
+			}
+		}
+		ids = append(ids, c.ID)
+	}
+	if !comicsTime.Equal(indexTime) {
+		if _, err = tx.Exec(ctx, clearIndex); err != nil {
+			return fmt.Errorf("error clear index: %w", err)
+		}
+		if _, err = tx.Exec(ctx, rebuildIndex); err != nil {
+			return fmt.Errorf("error rebuild index: %w", err)
+		}
+	} else {
+		if _, err = tx.Exec(ctx, clearComicsIndex, ids); err != nil {
+			return fmt.Errorf("error clear index of comics: %w", err)
+		}
+		if _, err = tx.Exec(ctx, extendIndex, ids); err != nil {
+			return fmt.Errorf("error index comics: %w", err)
 		}
-		if _, err := tx.Exec(ctx, insertComicsKeyword, comics.ID, keyword); err != nil {
-			return err
+	}
+	if _, err = tx.Exec(ctx, deleteOrphanKeywords); err != nil {
+		return fmt.Errorf("error delete orphan keywords: %w", err)
+	}
+	if _, err = tx.Exec(ctx, updateTimes, time.Now().UTC().Truncate(time.Microsecond)); err != nil {
+		return fmt.Errorf("error update last update time: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error commit: %w", err)
+	}
+	return nil
+}
+
+const getComicsText = `SELECT id, image_url, transcript, alt FROM comics ORDER BY id`
+
+func (pg *PostgresConn) GetComicsText(ctx context.Context) ([]domain.Comics, error) {
+	rows, err := pg.pool.Query(ctx, getComicsText)
+	if err != nil {
+		return nil, fmt.Errorf("error get query of comics: %w", err)
+	}
+	defer rows.Close()
+
+	comics := make([]domain.Comics, 0)
+	for rows.Next() {
+		c := domain.Comics{}
+		if err = rows.Scan(&c.ID, &c.ImgURL, &c.Transcript, &c.Alt); err != nil {
+			return nil, fmt.Errorf("error scan row: %w", err)
 		}
+		comics = append(comics, c)
+	}
 
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error rows: %w", err)
 	}
+	return comics, nil
+}
 
-	if err := tx.Commit(ctx); err != nil {
+const saveText = `UPDATE comics SET transcript = $2, alt = $3 WHERE id = $1`
+
+func (pg *PostgresConn) SaveText(ctx context.Context, comics []domain.Comics) error {
+	tx, err := pg.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error create tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, c := range comics {
+		if _, err = tx.Exec(ctx, saveText, c.ID, c.Transcript, c.Alt); err != nil {
+			return fmt.Errorf("error save text of comics %d: %w", c.ID, err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
 		return fmt.Errorf("error commit: %w", err)
 	}
 	return nil
 }
 
-const updateLastUpdateTime = `UPDATE time SET update_time_comics = $1 WHERE id = 1`
+const (
+	getGeneration      = `SELECT index_generation FROM time WHERE id = 1`
+	clearGenerations   = `DELETE FROM index WHERE generation <> $1`
+	insertGeneration   = `INSERT INTO index(keyword_id, comics_id, generation) SELECT id, $1, $3 FROM keyword WHERE keyword = ANY($2) ON CONFLICT DO NOTHING`
+	lockGeneration     = `SELECT index_generation FROM time WHERE id = 1 FOR UPDATE`
+	clearBuiltKeywords = `DELETE FROM comics_keyword WHERE comics_id IN (SELECT comics_id FROM index WHERE generation = $1)`
+	storeBuiltKeywords = `INSERT INTO comics_keyword(comics_id, keyword_id) SELECT comics_id, keyword_id FROM index WHERE generation = $1 ON CONFLICT DO NOTHING`
+	completeGeneration = `INSERT INTO index(keyword_id, comics_id, generation)
+SELECT keyword_id, comics_id, $1 FROM comics_keyword
+WHERE comics_id NOT IN (SELECT comics_id FROM index WHERE generation = $1)
+ON CONFLICT DO NOTHING`
+	switchGeneration = `UPDATE time SET index_generation = $1, update_time_comics = $2, update_time_index = $2 WHERE id = 1`
+)
+
+// BuildIndex indexes the keywords of the comics as a new generation in one
+// transaction. Comics without keywords are left out. The stored keywords and
+// the current generation stay as they are until SwitchIndex, so a build that
+// is never switched to changes nothing searches or the consistency check see.
+func (pg *PostgresConn) BuildIndex(ctx context.Context, comics []domain.Comics) (int, error) {
+	tx, err := pg.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error create tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
 
-func (pg *PostgresConn) Close(ctx context.Context, updateTime time.Time) error {
-	if _, err := pg.pool.Exec(ctx, updateLastUpdateTime, updateTime); err != nil {
-		return fmt.Errorf("error update last update time: %w", err)
+	var current int
+	if err = tx.QueryRow(ctx, getGeneration).Scan(&current); err != nil {
+		return 0, fmt.Errorf("error get index generation: %w", err)
+	}
+	// generations left by builds that were never switched to
+	if _, err = tx.Exec(ctx, clearGenerations, current); err != nil {
+		return 0, fmt.Errorf("error clear unused generations: %w", err)
+	}
+	generation := current + 1
+	for _, c := range comics {
+		if len(c.Keywords) == 0 {
+			continue
+		}
+		if _, err = tx.Exec(ctx, insertKeywords, c.Keywords); err != nil {
+			return 0, fmt.Errorf("error insert keywords of comics %d: %w", c.ID, err)
+		}
+		if _, err = tx.Exec(ctx, insertGeneration, c.ID, c.Keywords, generation); err != nil {
+			return 0, fmt.Errorf("error index comics %d: %w", c.ID, err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("error commit: %w", err)
+	}
+	return generation, nil
+}
+
+// SwitchIndex makes the generation the current index. The comics it covers
+// get its keywords stored, the others keep their stored keywords and are
+// indexed from them: comics left out of the build and comics added since.
+func (pg *PostgresConn) SwitchIndex(ctx context.Context, generation int) error {
+	tx, err := pg.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error create tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var current int
+	if err = tx.QueryRow(ctx, lockGeneration).Scan(&current); err != nil {
+		return fmt.Errorf("error lock index generation: %w", err)
+	}
+	if current != generation-1 {
+		return fmt.Errorf("error switch to index generation %d: current is %d", generation, current)
+	}
+	if _, err = tx.Exec(ctx, clearBuiltKeywords, generation); err != nil {
+		return fmt.Errorf("error clear keywords of reindexed comics: %w", err)
+	}
+	if _, err = tx.Exec(ctx, storeBuiltKeywords, generation); err != nil {
+		return fmt.Errorf("error store keywords of reindexed comics: %w", err)
+	}
+	if _, err = tx.Exec(ctx, completeGeneration, generation); err != nil {
+		return fmt.Errorf("error index comics outside the build: %w", err)
+	}
+	if _, err = tx.Exec(ctx, clearGenerations, generation); err != nil {
+		return fmt.Errorf("error clear previous generation: %w", err)
+	}
+	if _, err = tx.Exec(ctx, deleteOrphanKeywords); err != nil {
+		return fmt.Errorf("error delete orphan keywords: %w", err)
+	}
+	if _, err = tx.Exec(ctx, switchGeneration, generation, time.Now().UTC().Truncate(time.Microsecond)); err != nil {
+		return fmt.Errorf("error switch index generation: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error commit: %w", err)
 	}
-	pg.pool.Close()
 	return nil
 }
 
@@ -226,13 +467,6 @@ func (pg *PostgresConn) GetLastFullCheckTime(ctx context.Context) (time.Time, er
 
 const updateLastFullCheckTime = `UPDATE time SET last_full_check_time = $1 WHERE id = 1`
 
-func (pg *PostgresConn) UpdateLastFullCheckTime(ctx context.Context, updateTime time.Time) error {
-	if _, err := pg.pool.Exec(ctx, updateLastFullCheckTime, updateTime); err != nil {
-		return fmt.Errorf("error update last full check time: %w", err)
-	}
-	return nil
-}
-
 const getLastUpdateTime = `SELECT update_time_comics FROM time WHERE id = 1`
 
 func (pg *PostgresConn) GetLastUpdateTime(ctx context.Context) (time.Time, error) {
@@ -264,3 +498,24 @@ func (pg *PostgresConn) GetURLComicsByID(ctx context.Context, ID int) (string, e
 
 	return url, nil
 }
+
+const getRandomComics = `SELECT comics.id, comics.image_url FROM comics
+WHERE (SELECT COUNT(*) FROM comics_keyword
+	INNER JOIN keyword ON keyword.id = comics_keyword.keyword_id
+	WHERE comics_keyword.comics_id = comics.id AND keyword.keyword = ANY($1)) = COALESCE(cardinality($1::text[]), 0)
+ORDER BY random() LIMIT 1`
+
+func (pg *PostgresConn) GetRandomComics(ctx context.Context, keywords []string) (domain.ComicsInfo, error) {
+	c := domain.ComicsInfo{}
+	if err := pg.pool.QueryRow(ctx, getRandomComics, keywords).Scan(&c.ID, &c.ImgURL); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ComicsInfo{}, ports.ErrIsNotExist
+		}
+		return domain.ComicsInfo{}, fmt.Errorf("error get random comics: %w", err)
+	}
+	return c, nil
+}
+
+func (pg *PostgresConn) Ping(ctx context.Context) error {
+	return pg.pool.Ping(ctx)
+}
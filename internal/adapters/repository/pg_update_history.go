@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"yadro-project/internal/config"
+	"yadro-project/internal/core/domain"
+
+	"github.com/exaring/otelpgx"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type UpdateHistoryPostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewUpdateHistoryPostgresRepository(ctx context.Context, cfg config.PostgresDBConfig) (*UpdateHistoryPostgresRepository, error) {
+	pgCFG, err := pgxpool.ParseConfig(cfg.String())
+	if err != nil {
+		return nil, fmt.Errorf("error parse database config: %w", err)
+	}
+	pgCFG.ConnConfig.Tracer = otelpgx.NewTracer(otelpgx.WithAttributes(attribute.String("db.pool", "update_history")))
+
+	pool, err := pgxpool.NewWithConfig(ctx, pgCFG)
+	if err != nil {
+		return nil, fmt.Errorf("error create new postgres pool: %w", err)
+	}
+
+	return &UpdateHistoryPostgresRepository{
+		pool: pool,
+	}, nil
+}
+
+const insertUpdateRun = `INSERT INTO update_runs(scheduled_at, started_at, finished_at, outcome, new, total, error) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+func (pg *UpdateHistoryPostgresRepository) Append(ctx context.Context, run domain.UpdateRun) error {
+	if _, err := pg.pool.Exec(ctx, insertUpdateRun, run.ScheduledAt, run.StartedAt, run.FinishedAt, run.Outcome, run.New, run.Total, run.Error); err != nil {
+		return fmt.Errorf("error insert update run: %w", err)
+	}
+	return nil
+}
+
+const findUpdateRuns = `SELECT id, scheduled_at, started_at, finished_at, outcome, new, total, error FROM update_runs ORDER BY started_at DESC, id DESC LIMIT $1`
+
+func (pg *UpdateHistoryPostgresRepository) Find(ctx context.Context, limit int) ([]domain.UpdateRun, error) {
+	rows, err := pg.pool.Query(ctx, findUpdateRuns, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error query update runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := make([]domain.UpdateRun, 0)
+	for rows.Next() {
+		r := domain.UpdateRun{}
+		if err = rows.Scan(&r.ID, &r.ScheduledAt, &r.StartedAt, &r.FinishedAt, &r.Outcome, &r.New, &r.Total, &r.Error); err != nil {
+			return nil, fmt.Errorf("error scan: %w", err)
+		}
+		runs = append(runs, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error rows: %w", err)
+	}
+	return runs, nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"yadro-project/internal/core/domain"
+)
+
+type SecurityLogFile struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewSecurityLogFile(filePath string) (*SecurityLogFile, error) {
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("error open file \"%s\": %w", filePath, err)
+	}
+	return &SecurityLogFile{
+		file: file,
+	}, nil
+}
+
+func (l *SecurityLogFile) Write(ctx context.Context, event domain.SecurityEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error encode security event: %w", err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err = l.file.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("error write security event: %w", err)
+	}
+	return nil
+}
+
+func (l *SecurityLogFile) Close() error {
+	return l.file.Close()
+}
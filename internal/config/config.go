@@ -14,7 +14,7 @@ type JsonDBConfig struct {
 
 type PostgresDBConfig struct {
 	User     string `yaml:"user"`
-	Password string `yaml:"password"`
+	Password string `yaml:"password" secret:"true"`
 	Host     string `yaml:"host"`
 	Port     int    `yaml:"port"`
 	Database string `yaml:"database"`
@@ -33,14 +33,89 @@ type IndexConfig struct {
 	IndexFile string `yaml:"index_file"`
 }
 
+type RatePolicyConfig struct {
+	Route string        `yaml:"route"`
+	Role  string        `yaml:"role"`
+	Key   string        `yaml:"key"`
+	Limit int           `yaml:"limit"`
+	Per   time.Duration `yaml:"per"`
+	Burst int           `yaml:"burst"`
+}
+
+type LegacyRoutesConfig struct {
+	Enabled      bool      `yaml:"enabled"`
+	DeprecatedAt time.Time `yaml:"deprecated_at"`
+	Sunset       time.Time `yaml:"sunset"`
+}
+
 type ServerConfig struct {
-	Port             int `yaml:"port"`
-	ConcurrencyLimit int `yaml:"concurrency_limit"`
-	RateLimit        int `yaml:"rate_limit"`
+	Port               int                `yaml:"port"`
+	ConcurrencyLimit   int                `yaml:"concurrency_limit"`
+	ConcurrencyQueue   int                `yaml:"concurrency_queue"`
+	ConcurrencyTimeout time.Duration      `yaml:"concurrency_timeout"`
+	RateLimit          int                `yaml:"rate_limit"`
+	RateBurst          int                `yaml:"rate_burst"`
+	RatePolicies       []RatePolicyConfig `yaml:"rate_policies"`
+	RateLimitBackend   string             `yaml:"rate_limit_backend"`
+	LimiterIdleTimeout time.Duration      `yaml:"limiter_idle_timeout"`
+	LimiterMaxEntries  int                `yaml:"limiter_max_entries"`
+	LegacyRoutes       LegacyRoutesConfig `yaml:"legacy_routes"`
+	InstanceID         string             `yaml:"instance_id"`
+	DrainDelay         time.Duration      `yaml:"drain_delay"`
+	ShutdownTimeout    time.Duration      `yaml:"shutdown_timeout"`
+}
+
+type LoginGuardConfig struct {
+	MaxFailuresPerIP      int           `yaml:"max_failures_per_ip"`
+	MaxFailuresPerAccount int           `yaml:"max_failures_per_account"`
+	FreeAttempts          int           `yaml:"free_attempts"`
+	BaseDelay             time.Duration `yaml:"base_delay"`
+	MaxDelay              time.Duration `yaml:"max_delay"`
+	LockoutTime           time.Duration `yaml:"lockout_time"`
+	FailureWindow         time.Duration `yaml:"failure_window"`
+	SecurityLogFile       string        `yaml:"security_log_file"`
+}
+
+type SessionConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	CookieName     string `yaml:"cookie_name"`
+	CSRFCookieName string `yaml:"csrf_cookie_name"`
+	Secure         bool   `yaml:"secure"`
 }
 
 type AuthConfig struct {
-	TokenMaxTime time.Duration `yaml:"token_max_time"`
+	TokenMaxTime time.Duration    `yaml:"token_max_time"`
+	LoginGuard   LoginGuardConfig `yaml:"login_guard"`
+	Session      SessionConfig    `yaml:"session"`
+}
+
+type UpdateConfig struct {
+	Schedule          string        `yaml:"schedule"`
+	Jitter            time.Duration `yaml:"jitter"`
+	FullCheckInterval time.Duration `yaml:"full_check_interval"`
+	LeaseTTL          time.Duration `yaml:"lease_ttl"`
+}
+
+type LogConfig struct {
+	Format string `yaml:"format"`
+	Level  string `yaml:"level"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+type SearchConfig struct {
+	CacheSize int           `yaml:"cache_size"`
+	CacheTTL  time.Duration `yaml:"cache_ttl"`
+}
+
+type DailyConfig struct {
+	NoRepeatDays int `yaml:"no_repeat_days"`
 }
 
 type Config struct {
@@ -49,8 +124,15 @@ type Config struct {
 	IndexCFG IndexConfig      `yaml:"index"`
 	SrvCFG   ServerConfig     `yaml:"server"`
 	AuthCFG  AuthConfig       `yaml:"auth"`
+	LogCFG   LogConfig        `yaml:"log"`
+	TraceCFG TracingConfig    `yaml:"tracing"`
+	UpdCFG   UpdateConfig     `yaml:"update"`
+	SrchCFG  SearchConfig     `yaml:"search"`
+	DailyCFG DailyConfig      `yaml:"daily"`
 }
 
+// NewConfig reads the yaml file over the defaults, applies XKCD_* environment
+// overrides and validates the result.
 func NewConfig(c string) (Config, error) {
 	if c == "" {
 		c = "config.yaml"
@@ -59,45 +141,140 @@ func NewConfig(c string) (Config, error) {
 	if err != nil {
 		return Config{}, err
 	}
+	defer file.Close()
+	// defaults go first, so a setting left out keeps its default and an
+	// explicit zero is kept
 	cfg := Config{}
+	cfg.SetDefault()
 	if err = yaml.NewDecoder(file).Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("error decode %s: %w", c, err)
+	}
+	if err = applyEnv(&cfg); err != nil {
+		return Config{}, fmt.Errorf("error apply environment: %w", err)
+	}
+	cfg.SrvCFG.setPolicyDefaults()
+	if err = cfg.Validate(); err != nil {
 		return Config{}, err
 	}
 	return cfg, nil
 }
 
+// SetDefault sets every setting to its default, the file and the environment
+// are applied over them.
 func (c *Config) SetDefault() {
+	c.DbCFG.SetDefault()
 	c.AppCFG.SetDefault()
 	c.IndexCFG.SetDefault()
 	c.SrvCFG.SetDefault()
+	c.AuthCFG.SetDefault()
+	c.LogCFG.SetDefault()
+	c.TraceCFG.SetDefault()
+	c.UpdCFG.SetDefault()
+	c.SrchCFG.SetDefault()
+	c.DailyCFG.SetDefault()
+}
+
+func (c *DailyConfig) SetDefault() {
+	c.NoRepeatDays = 90
+}
+
+func (c *SearchConfig) SetDefault() {
+	c.CacheSize = 1000
+	c.CacheTTL = time.Minute * 5
+}
+
+func (c *UpdateConfig) SetDefault() {
+	c.Schedule = "0 3 * * *"
+	c.FullCheckInterval = time.Hour * 24 * 30
+	c.LeaseTTL = time.Second * 30
+}
+
+func (c *TracingConfig) SetDefault() {
+	c.Exporter = "none"
+	c.Endpoint = "localhost:4318"
+	c.ServiceName = "xkcd-server"
+	c.SampleRatio = 1
+}
+
+func (c *LogConfig) SetDefault() {
+	c.Format = "json"
+	c.Level = "info"
+}
+
+func (c *PostgresDBConfig) SetDefault() {
+	c.Host = "localhost"
+	c.Port = 5432
 }
 
 func (c *AppConfig) SetDefault() {
-	if c.SourceURL == "" {
-		c.SourceURL = "xkcd.com"
-	}
+	c.SourceURL = "xkcd.com"
+	c.Parallel = 10
 }
 
 func (c *IndexConfig) SetDefault() {
-	if c.IndexFile == "" {
-		c.IndexFile = "index.json"
-	}
+	c.IndexFile = "index.json"
 }
 
 func (c *ServerConfig) SetDefault() {
-	if c.Port == 0 {
-		c.Port = 9000
+	c.Port = 9000
+	c.ConcurrencyLimit = 10
+	c.ConcurrencyQueue = 100
+	c.ConcurrencyTimeout = time.Second * 10
+	c.RateBurst = 10
+	c.LimiterIdleTimeout = time.Minute * 10
+	c.LimiterMaxEntries = 10000
+	c.RateLimitBackend = "memory"
+	c.InstanceID, _ = os.Hostname()
+	c.DrainDelay = time.Second * 5
+	c.ShutdownTimeout = time.Second * 30
+	// root routes stay until the sunset, with deprecation headers
+	c.LegacyRoutes.Enabled = true
+}
+
+// setPolicyDefaults fills the policies read from the file. Without any, the
+// legacy rate_limit still limits POST /update.
+func (c *ServerConfig) setPolicyDefaults() {
+	if len(c.RatePolicies) == 0 && c.RateLimit > 0 {
+		c.RatePolicies = []RatePolicyConfig{{
+			Route: "POST /update",
+			Key:   "user",
+			Limit: c.RateLimit,
+			Per:   time.Second,
+			Burst: c.RateBurst,
+		}}
 	}
-	if c.ConcurrencyLimit == 0 {
-		c.ConcurrencyLimit = 10
+	for i := range c.RatePolicies {
+		c.RatePolicies[i].SetDefault()
+	}
+}
+
+func (c *RatePolicyConfig) SetDefault() {
+	if c.Key == "" {
+		c.Key = "user"
 	}
-	if c.RateLimit == 0 {
-		c.RateLimit = 0
+	if c.Per == 0 {
+		c.Per = time.Second
 	}
 }
 
 func (c *AuthConfig) SetDefault() {
-	if c.TokenMaxTime == 0 {
-		c.TokenMaxTime = time.Second * 10
-	}
+	c.TokenMaxTime = time.Second * 10
+	c.LoginGuard.SetDefault()
+	c.Session.SetDefault()
+}
+
+func (c *SessionConfig) SetDefault() {
+	c.CookieName = "session"
+	c.CSRFCookieName = "csrf_token"
+}
+
+func (c *LoginGuardConfig) SetDefault() {
+	c.MaxFailuresPerIP = 50
+	c.MaxFailuresPerAccount = 10
+	c.FreeAttempts = 3
+	c.BaseDelay = time.Second
+	c.MaxDelay = time.Minute
+	c.LockoutTime = time.Minute * 15
+	c.FailureWindow = time.Hour
+	c.SecurityLogFile = "security.log"
 }
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfig = `
+database:
+  user: "xkcd"
+  host: "db"
+  database: "comics"
+server:
+  rate_policies:
+    - route: "GET /pics"
+      limit: 60
+      per: 1m
+`
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewConfig_Env(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(secret, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XKCD_DATABASE_PASSWORD_FILE", secret)
+	t.Setenv("XKCD_SERVER_PORT", "8080")
+	t.Setenv("XKCD_AUTH_TOKEN_MAX_TIME", "1h")
+	t.Setenv("XKCD_SERVER_RATE_POLICIES", `[{route: "POST /login", key: ip, limit: 5, per: 1m}]`)
+
+	cfg, err := NewConfig(writeConfig(t, testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DbCFG.Password != "s3cret" {
+		t.Errorf("got password %q, want it from the file", cfg.DbCFG.Password)
+	}
+	if cfg.SrvCFG.Port != 8080 || cfg.AuthCFG.TokenMaxTime != time.Hour {
+		t.Errorf("got port %d and token max time %s, want overrides", cfg.SrvCFG.Port, cfg.AuthCFG.TokenMaxTime)
+	}
+	if p := cfg.SrvCFG.RatePolicies; len(p) != 1 || p[0].Route != "POST /login" || p[0].Key != "ip" {
+		t.Errorf("got rate policies %+v, want the override", p)
+	}
+	if cfg.AppCFG.Parallel == 0 || cfg.LogCFG.Level == "" || !cfg.SrvCFG.LegacyRoutes.Enabled {
+		t.Errorf("defaults are not applied: %+v", cfg)
+	}
+
+	out, err := cfg.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "s3cret") {
+		t.Errorf("printed config leaks the password:\n%s", out)
+	}
+}
+
+func TestNewConfig_Invalid(t *testing.T) {
+	t.Setenv("XKCD_SERVER_RATE_LIMIT_BACKEND", "redis")
+	t.Setenv("XKCD_LOG_LEVEL", "loud")
+
+	_, err := NewConfig(writeConfig(t, testConfig))
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidConfig)
+	}
+	for _, want := range []string{"server.rate_limit_backend", "log.level"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error doesn't mention %s: %v", want, err)
+		}
+	}
+}
+
+func TestNewConfig_ExplicitZero(t *testing.T) {
+	cfg, err := NewConfig(writeConfig(t, testConfig+`
+  drain_delay: 0s
+  concurrency_queue: 0
+tracing:
+  sample_ratio: 0
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SrvCFG.DrainDelay != 0 || cfg.SrvCFG.ConcurrencyQueue != 0 || cfg.TraceCFG.SampleRatio != 0 {
+		t.Errorf("got drain delay %s, queue %d, sample ratio %g, want explicit zeros kept",
+			cfg.SrvCFG.DrainDelay, cfg.SrvCFG.ConcurrencyQueue, cfg.TraceCFG.SampleRatio)
+	}
+	if cfg.SrvCFG.ShutdownTimeout == 0 || cfg.SrvCFG.ConcurrencyLimit == 0 {
+		t.Errorf("settings left out lost their defaults: %+v", cfg.SrvCFG)
+	}
+}
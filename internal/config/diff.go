@@ -0,0 +1,63 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Diff returns the yaml paths of the settings that differ between the
+// configs, e.g. "server.rate_policies". Slices are compared as a whole.
+func Diff(old, new Config) []string {
+	return diff("", reflect.ValueOf(old), reflect.ValueOf(new))
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func diff(path string, a, b reflect.Value) []string {
+	if a.Kind() != reflect.Struct || a.Type() == timeType {
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return nil
+		}
+		return []string{path}
+	}
+	var changed []string
+	for i := 0; i < a.NumField(); i++ {
+		name, _, _ := strings.Cut(a.Type().Field(i).Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if path != "" {
+			name = path + "." + name
+		}
+		changed = append(changed, diff(name, a.Field(i), b.Field(i))...)
+	}
+	return changed
+}
+
+// Copy sets the setting at the yaml path, e.g. "server.rate_policies", in dst
+// to its value in src. It reports false if there is no such setting.
+func Copy(dst *Config, src Config, path string) bool {
+	d, s := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src)
+	for _, name := range strings.Split(path, ".") {
+		if d.Kind() != reflect.Struct || d.Type() == timeType {
+			return false
+		}
+		i := fieldByTag(d.Type(), name)
+		if i < 0 {
+			return false
+		}
+		d, s = d.Field(i), s.Field(i)
+	}
+	d.Set(s)
+	return true
+}
+
+func fieldByTag(t reflect.Type, name string) int {
+	for i := 0; i < t.NumField(); i++ {
+		if tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ","); tag == name {
+			return i
+		}
+	}
+	return -1
+}
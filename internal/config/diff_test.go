@@ -0,0 +1,45 @@
+package config
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	old := Config{
+		DbCFG:   PostgresDBConfig{Host: "db"},
+		SrvCFG:  ServerConfig{Port: 9000, RatePolicies: []RatePolicyConfig{{Route: "GET /pics", Limit: 60}}},
+		AuthCFG: AuthConfig{TokenMaxTime: time.Minute},
+	}
+	new := old
+	new.DbCFG.Host = "localhost"
+	new.SrvCFG.RatePolicies = []RatePolicyConfig{{Route: "GET /pics", Limit: 120}}
+	new.SrvCFG.LegacyRoutes.Sunset = time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	got := Diff(old, new)
+	want := []string{"database.host", "server.rate_policies", "server.legacy_routes.sunset"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := Diff(old, old); len(got) != 0 {
+		t.Errorf("got %v for equal configs, want none", got)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	dst := Config{SrvCFG: ServerConfig{Port: 9000, ConcurrencyLimit: 10}}
+	src := Config{SrvCFG: ServerConfig{Port: 8080, ConcurrencyLimit: 20}}
+
+	if !Copy(&dst, src, "server.concurrency_limit") {
+		t.Fatal("setting is not found")
+	}
+	if dst.SrvCFG.ConcurrencyLimit != 20 || dst.SrvCFG.Port != 9000 {
+		t.Errorf("got %+v, want only the concurrency limit copied", dst.SrvCFG)
+	}
+	for _, path := range []string{"server.nope", "server.port.value", ""} {
+		if Copy(&dst, src, path) {
+			t.Errorf("copied unknown setting %q", path)
+		}
+	}
+}
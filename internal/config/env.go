@@ -0,0 +1,66 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	envPrefix     = "XKCD"
+	envFileSuffix = "_FILE"
+)
+
+// applyEnv overrides settings from environment variables named after their
+// yaml path, e.g. XKCD_DATABASE_PASSWORD for database.password. A variable
+// with the _FILE suffix names a file holding the value, which keeps secrets
+// out of the environment. Values are parsed as yaml, so durations, lists and
+// policies are written the same way as in the config file.
+func applyEnv(cfg *Config) error {
+	return applyEnvTo(envPrefix, reflect.ValueOf(cfg).Elem())
+}
+
+func applyEnvTo(name string, v reflect.Value) error {
+	if v.Kind() == reflect.Struct && v.Type() != timeType {
+		var errs []error
+		for i := 0; i < v.NumField(); i++ {
+			tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+			if tag == "" || tag == "-" {
+				continue
+			}
+			if err := applyEnvTo(name+"_"+strings.ToUpper(tag), v.Field(i)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	value, ok := os.LookupEnv(name)
+	if file, fok := os.LookupEnv(name + envFileSuffix); fok {
+		if ok {
+			return fmt.Errorf("%s and %s%s are both set", name, name, envFileSuffix)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("error read %s%s: %w", name, envFileSuffix, err)
+		}
+		value, ok = strings.TrimRight(string(data), "\r\n"), true
+	}
+	if !ok {
+		return nil
+	}
+	if v.Kind() == reflect.String {
+		v.SetString(value)
+		return nil
+	}
+	parsed := reflect.New(v.Type())
+	if err := yaml.Unmarshal([]byte(value), parsed.Interface()); err != nil {
+		return fmt.Errorf("error parse %s: %w", name, err)
+	}
+	v.Set(parsed.Elem())
+	return nil
+}
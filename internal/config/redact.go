@@ -0,0 +1,34 @@
+package config
+
+import (
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "******"
+
+// Redacted returns a copy of the config with the fields tagged secret masked.
+func (c Config) Redacted() Config {
+	redact(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+func redact(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		switch {
+		case v.Type().Field(i).Tag.Get("secret") == "true" && f.Kind() == reflect.String:
+			if f.String() != "" {
+				f.SetString(redacted)
+			}
+		case f.Kind() == reflect.Struct && f.Type() != timeType:
+			redact(f)
+		}
+	}
+}
+
+// YAML marshals the config with secrets redacted.
+func (c Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c.Redacted())
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+var ( //errors
+	ErrInvalidConfig = errors.New("invalid config")
+)
+
+// Validate checks the config after defaults are applied and reports every
+// problem at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.DbCFG.Host != "", "database.host is required")
+	check(c.DbCFG.User != "", "database.user is required")
+	check(c.DbCFG.Database != "", "database.database is required")
+	check(c.DbCFG.Port > 0 && c.DbCFG.Port < 1<<16, "database.port %d is out of range", c.DbCFG.Port)
+
+	check(c.AppCFG.SourceURL != "", "app.source_url is required")
+	check(c.AppCFG.Parallel > 0, "app.parallel must be positive, got %d", c.AppCFG.Parallel)
+
+	s := c.SrvCFG
+	check(s.Port > 0 && s.Port < 1<<16, "server.port %d is out of range", s.Port)
+	check(s.ConcurrencyLimit > 0, "server.concurrency_limit must be positive, got %d", s.ConcurrencyLimit)
+	check(s.ConcurrencyQueue >= 0, "server.concurrency_queue must not be negative, got %d", s.ConcurrencyQueue)
+	check(s.ConcurrencyTimeout >= 0, "server.concurrency_timeout must not be negative, got %s", s.ConcurrencyTimeout)
+	check(slices.Contains([]string{"memory", "postgres"}, s.RateLimitBackend), "server.rate_limit_backend %q is not one of memory, postgres", s.RateLimitBackend)
+	check(s.LimiterMaxEntries >= 0, "server.limiter_max_entries must not be negative, got %d", s.LimiterMaxEntries)
+	check(s.InstanceID != "", "server.instance_id is required")
+	check(s.DrainDelay >= 0, "server.drain_delay must not be negative, got %s", s.DrainDelay)
+	check(s.ShutdownTimeout > 0, "server.shutdown_timeout must be positive, got %s", s.ShutdownTimeout)
+	for i, p := range s.RatePolicies {
+		check(p.Route != "", "server.rate_policies[%d].route is required", i)
+		check(slices.Contains([]string{"user", "ip"}, p.Key), "server.rate_policies[%d].key %q is not one of user, ip", i, p.Key)
+		check(p.Limit > 0, "server.rate_policies[%d].limit must be positive, got %d", i, p.Limit)
+		check(p.Per > 0, "server.rate_policies[%d].per must be positive, got %s", i, p.Per)
+		check(p.Burst >= 0, "server.rate_policies[%d].burst must not be negative, got %d", i, p.Burst)
+	}
+	if l := s.LegacyRoutes; !l.DeprecatedAt.IsZero() && !l.Sunset.IsZero() {
+		check(l.Sunset.After(l.DeprecatedAt), "server.legacy_routes.sunset must be after deprecated_at")
+	}
+
+	a := c.AuthCFG
+	check(a.TokenMaxTime > 0, "auth.token_max_time must be positive, got %s", a.TokenMaxTime)
+	check(a.LoginGuard.MaxDelay >= a.LoginGuard.BaseDelay, "auth.login_guard.max_delay must not be less than base_delay")
+	check(a.LoginGuard.SecurityLogFile != "", "auth.login_guard.security_log_file is required")
+
+	check(slices.Contains([]string{"json", "text"}, strings.ToLower(c.LogCFG.Format)), "log.format %q is not one of json, text", c.LogCFG.Format)
+	check(slices.Contains([]string{"debug", "info", "warn", "error"}, strings.ToLower(c.LogCFG.Level)), "log.level %q is not one of debug, info, warn, error", c.LogCFG.Level)
+
+	check(slices.Contains([]string{"none", "otlp", "stdout"}, c.TraceCFG.Exporter), "tracing.exporter %q is not one of none, otlp, stdout", c.TraceCFG.Exporter)
+	check(c.TraceCFG.SampleRatio >= 0 && c.TraceCFG.SampleRatio <= 1, "tracing.sample_ratio must be in [0, 1], got %g", c.TraceCFG.SampleRatio)
+
+	_, err := cron.ParseStandard(c.UpdCFG.Schedule)
+	check(err == nil, "update.schedule %q is not a cron expression: %v", c.UpdCFG.Schedule, err)
+	check(c.UpdCFG.Jitter >= 0, "update.jitter must not be negative, got %s", c.UpdCFG.Jitter)
+	check(c.UpdCFG.LeaseTTL >= time.Second, "update.lease_ttl must be at least 1s, got %s", c.UpdCFG.LeaseTTL)
+	check(c.UpdCFG.FullCheckInterval > 0, "update.full_check_interval must be positive, got %s", c.UpdCFG.FullCheckInterval)
+
+	check(c.SrchCFG.CacheSize > 0, "search.cache_size must be positive, got %d", c.SrchCFG.CacheSize)
+	check(c.SrchCFG.CacheTTL > 0, "search.cache_ttl must be positive, got %s", c.SrchCFG.CacheTTL)
+
+	check(c.DailyCFG.NoRepeatDays >= 0, "daily.no_repeat_days must not be negative, got %d", c.DailyCFG.NoRepeatDays)
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w:\n%w", ErrInvalidConfig, err)
+	}
+	return nil
+}
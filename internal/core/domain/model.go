@@ -3,12 +3,48 @@ package domain
 import (
 	"fmt"
 	"strings"
+	"time"
+	"unicode"
 )
 
 type Comics struct {
-	ID       int      `json:"-"`
-	ImgURL   string   `json:"img_url"`
-	Keywords []string `json:"keywords,omitempty"`
+	ID         int      `json:"-"`
+	ImgURL     string   `json:"img_url"`
+	Keywords   []string `json:"keywords,omitempty"`
+	Transcript string   `json:"-"`
+	Alt        string   `json:"-"`
+}
+
+// Words splits the transcript and the alt text into the words to stem. The
+// alt text is skipped when the transcript already ends with it.
+func (c Comics) Words() []string {
+	splitted := SplitWords(c.Transcript)
+	flag := false
+	for i := len(splitted) - 1; i >= 0; i-- {
+		if splitted[i] == "alt" || splitted[i] == "title" {
+			flag = true
+			if i < len(splitted)-1 && splitted[i+1] == "text" {
+				splitted = append(splitted[:i], splitted[i+2:]...)
+			} else {
+				splitted = append(splitted[:i], splitted[i+1:]...)
+			}
+			break
+		}
+	}
+	if !flag {
+		splitted = append(splitted, SplitWords(c.Alt)...)
+	}
+	return splitted
+}
+
+// SplitWords splits a text into words of letters and apostrophes, the way
+// comics and search phrases are tokenised before stemming.
+func SplitWords(text string) []string {
+	return strings.FieldsFunc(text, isNotWordRune)
+}
+
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && r != '\''
 }
 
 type UpdateMeta struct {
@@ -16,6 +52,47 @@ type UpdateMeta struct {
 	Total int `json:"total"`
 }
 
+// ComicsVersion identifies the stored comics and the index that results are
+// computed from. It changes on every update and reindex.
+type ComicsVersion struct {
+	Generation int
+	UpdatedAt  time.Time
+}
+
+func (v ComicsVersion) Equal(other ComicsVersion) bool {
+	return v.Generation == other.Generation && v.UpdatedAt.Equal(other.UpdatedAt)
+}
+
+// RelatedComics is a comics similar to another one. Score is the cosine
+// similarity of their keywords, from 0 to 1.
+type RelatedComics struct {
+	ID     int     `json:"id"`
+	ImgURL string  `json:"img_url"`
+	Score  float64 `json:"score"`
+}
+
+// ComicsInfo is a comics as served by the /comics endpoints.
+type ComicsInfo struct {
+	ID     int    `json:"id"`
+	ImgURL string `json:"img_url"`
+}
+
+// DailyComics is the comics of the day, Date is a UTC date as YYYY-MM-DD.
+type DailyComics struct {
+	Date string `json:"date"`
+	ComicsInfo
+}
+
+// ReindexMeta describes a reindex. Fetched is how many comics had no stored
+// text and were fetched again, Skipped how many of them could not be fetched
+// and kept their keywords.
+type ReindexMeta struct {
+	Generation int `json:"generation"`
+	Comics     int `json:"comics"`
+	Fetched    int `json:"fetched"`
+	Skipped    int `json:"skipped"`
+}
+
 func (c Comics) String() string {
 	return fmt.Sprintf("ID: %d\nimg_url: %s\nkeywords: \"%s\"", c.ID, c.ImgURL, strings.Join(c.Keywords, "\", \""))
 }
@@ -24,3 +101,155 @@ type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
+
+type SecurityEvent struct {
+	Time        time.Time `json:"time"`
+	Type        string    `json:"type"`
+	Email       string    `json:"email,omitempty"`
+	IP          string    `json:"ip,omitempty"`
+	Failures    int       `json:"failures,omitempty"`
+	LockedUntil time.Time `json:"locked_until,omitempty"`
+}
+
+const ( // security event types
+	SecurityEventLockout            = "lockout"
+	SecurityEventLoginAfterFailures = "login_after_failures"
+)
+
+type AuditEvent struct {
+	ID      int64     `json:"id"`
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`
+	IP      string    `json:"ip,omitempty"`
+	Action  string    `json:"action"`
+	Outcome string    `json:"outcome"`
+	Details string    `json:"details,omitempty"`
+}
+
+type AuditFilter struct {
+	Actor   string
+	IP      string
+	Action  string
+	Outcome string
+	From    time.Time
+	To      time.Time
+	Limit   int
+	Offset  int
+}
+
+const ( // audit actions
+	AuditActionLogin        = "login"
+	AuditActionLoginFailed  = "login_failed"
+	AuditActionUpdate       = "update"
+	AuditActionConfigReload = "config_reload"
+	AuditActionRepair       = "consistency_repair"
+	AuditActionReindex      = "reindex"
+)
+
+const ( // audit outcomes
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+	AuditOutcomeDenied  = "denied"
+)
+
+const ( // roles
+	RoleAdmin     = "admin"
+	RoleUser      = "user"
+	RoleAnonymous = "anonymous"
+)
+
+type RateLimitRule struct {
+	Limit int
+	Per   time.Duration
+	Burst int
+}
+
+// Rate returns how many tokens are added to the bucket per second.
+func (r RateLimitRule) Rate() float64 {
+	if r.Per <= 0 {
+		return float64(r.Limit)
+	}
+	return float64(r.Limit) / r.Per.Seconds()
+}
+
+// Capacity returns the size of the bucket, which is Limit unless Burst is set.
+func (r RateLimitRule) Capacity() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Limit
+}
+
+type RateLimitStatus struct {
+	Limit      int           `json:"limit"`
+	Remaining  int           `json:"remaining"`
+	RetryAfter time.Duration `json:"-"`
+}
+
+const ( // health statuses
+	HealthStatusOK       = "ok"
+	HealthStatusFailed   = "failed"
+	HealthStatusStale    = "stale"
+	HealthStatusReady    = "ready"
+	HealthStatusNotReady = "not_ready"
+	HealthStatusDraining = "draining"
+)
+
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// Readiness is public, so checks carry no error details, they are logged.
+// A degraded server is still ready, e.g. search falls back to the
+// repository while the index is stale.
+type Readiness struct {
+	Status        string        `json:"status"`
+	Degraded      bool          `json:"degraded"`
+	UpdateRunning bool          `json:"update_running"`
+	Checks        []HealthCheck `json:"checks"`
+}
+
+const ( // update run outcomes
+	UpdateRunSuccess = "success"
+	UpdateRunFailure = "failure"
+	UpdateRunSkipped = "skipped"
+)
+
+// UpdateRun is a scheduled update. ScheduledAt is the time from the schedule,
+// StartedAt is later by the jitter.
+type UpdateRun struct {
+	ID          int64     `json:"id"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	Outcome     string    `json:"outcome"`
+	New         int       `json:"new"`
+	Total       int       `json:"total"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Lease is a named lock held by one instance until it expires.
+type Lease struct {
+	Name      string    `json:"name"`
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ConsistencyReport lists the differences between the stored comics and the
+// index. Comics are reported by ID. Repaired is set by a repair and lists the
+// comics that were reindexed.
+type ConsistencyReport struct {
+	IndexFresh      bool     `json:"index_fresh"`
+	MissingPostings []int    `json:"missing_postings"`
+	OrphanPostings  []int    `json:"orphan_postings"`
+	EmptyComics     []int    `json:"empty_comics"`
+	OrphanKeywords  []string `json:"orphan_keywords"`
+	Repaired        []int    `json:"repaired,omitempty"`
+}
+
+// Consistent reports whether the index matches the stored comics.
+func (r ConsistencyReport) Consistent() bool {
+	return r.IndexFresh && len(r.MissingPostings) == 0 && len(r.OrphanPostings) == 0 &&
+		len(r.EmptyComics) == 0 && len(r.OrphanKeywords) == 0
+}
@@ -0,0 +1,11 @@
+package ports
+
+import (
+	"context"
+	"yadro-project/internal/core/domain"
+)
+
+type AuditRepository interface {
+	Append(ctx context.Context, event domain.AuditEvent) error
+	Find(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent, error)
+}
@@ -0,0 +1,19 @@
+package ports
+
+import (
+	"context"
+	"time"
+	"yadro-project/internal/core/domain"
+)
+
+// DailyRepository keeps the comics of the day. Days are UTC dates.
+type DailyRepository interface {
+	// Get returns ErrIsNotExist if no comics was chosen for the day yet.
+	Get(ctx context.Context, day time.Time) (domain.DailyComics, error)
+	// Candidates returns the IDs, ascending, of the comics not chosen in the
+	// days days before the day.
+	Candidates(ctx context.Context, day time.Time, days int) ([]int, error)
+	// Save chooses the comics for the day unless another one already is and
+	// returns the chosen one.
+	Save(ctx context.Context, day time.Time, ID int) (domain.DailyComics, error)
+}
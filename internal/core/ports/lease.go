@@ -0,0 +1,16 @@
+package ports
+
+import (
+	"context"
+	"time"
+	"yadro-project/internal/core/domain"
+)
+
+type LeaseRepository interface {
+	// Acquire takes the lease, or extends it if the holder already has it.
+	// It reports false if another holder has an unexpired lease.
+	Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	// Get returns the unexpired lease or ErrIsNotExist.
+	Get(ctx context.Context, name string) (domain.Lease, error)
+	Release(ctx context.Context, name, holder string) error
+}
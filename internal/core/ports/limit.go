@@ -0,0 +1,15 @@
+package ports
+
+import (
+	"context"
+	"errors"
+	"yadro-project/internal/core/domain"
+)
+
+var ( //errors
+	ErrLimitExceeded = errors.New("limit exceeded")
+)
+
+type RateLimiter interface {
+	Take(ctx context.Context, key string, rule domain.RateLimitRule) (domain.RateLimitStatus, error)
+}
@@ -0,0 +1,19 @@
+package ports
+
+import "time"
+
+// Metrics records what the services observe. An adapter exports it.
+type Metrics interface {
+	// ObserveSearch records a search by its source: index or repository.
+	ObserveSearch(source string, d time.Duration)
+	// SearchCacheLookup records a lookup by its result: hit or miss.
+	SearchCacheLookup(result string)
+	SetSearchCacheEntries(n int)
+	// ObserveCrawl records fetching comics by mode: full or part.
+	ObserveCrawl(mode string, d time.Duration)
+	ScheduledUpdate(outcome string)
+	AddAdmissionActive(delta int)
+	AddAdmissionQueued(delta int)
+	// AdmissionRejected records a rejection by reason: queue_full or timeout.
+	AdmissionRejected(reason string)
+}
@@ -16,12 +16,29 @@ type ComicsRepository interface {
 	GetComics(ctx context.Context) ([]domain.Comics, error)
 	GetCountComics(ctx context.Context) (int, error)
 	GetIDMissingComics(ctx context.Context, cntInServer int) ([]int, error)
-	Add(ctx context.Context, comics domain.Comics, id int) error
-	Close(ctx context.Context, updateTime time.Time) error
+	// Commit atomically stores the new comics, indexes them and sets the
+	// update times. It returns how many comics were new and whether the
+	// index changed, a stale index is rebuilt even without new comics.
+	Commit(ctx context.Context, comics []domain.Comics, full bool) (int, bool, error)
 	GetLastFullCheckTime(ctx context.Context) (time.Time, error)
-	UpdateLastFullCheckTime(ctx context.Context, updateTime time.Time) error
+	CheckConsistency(ctx context.Context) (domain.ConsistencyReport, error)
+	// RepairIndex replaces the index postings of the comics with their stored
+	// keywords, storing the keywords given with a comics first. A stale index
+	// is rebuilt whole. Both update times are set.
+	RepairIndex(ctx context.Context, comics []domain.Comics) error
 	GetLastUpdateTime(ctx context.Context) (time.Time, error)
 	GetURLComicsByID(ctx context.Context, ID int) (string, error)
+	// GetRandomComics returns a random comics having all the keywords, or
+	// ErrIsNotExist if there is none.
+	GetRandomComics(ctx context.Context, keywords []string) (domain.ComicsInfo, error)
+	GetComicsText(ctx context.Context) ([]domain.Comics, error)
+	SaveText(ctx context.Context, comics []domain.Comics) error
+	// BuildIndex indexes the keywords of the comics as a new generation, which
+	// it returns, leaving out comics without keywords. SwitchIndex makes it the
+	// one searches use and stores its keywords; the comics it doesn't cover
+	// are indexed from their stored keywords.
+	BuildIndex(ctx context.Context, comics []domain.Comics) (int, error)
+	SwitchIndex(ctx context.Context, generation int) error
 }
 
 type AuthRepository interface {
@@ -0,0 +1,10 @@
+package ports
+
+import (
+	"context"
+	"yadro-project/internal/core/domain"
+)
+
+type SecurityLog interface {
+	Write(ctx context.Context, event domain.SecurityEvent) error
+}
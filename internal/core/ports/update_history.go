@@ -0,0 +1,11 @@
+package ports
+
+import (
+	"context"
+	"yadro-project/internal/core/domain"
+)
+
+type UpdateHistoryRepository interface {
+	Append(ctx context.Context, run domain.UpdateRun) error
+	Find(ctx context.Context, limit int) ([]domain.UpdateRun, error)
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"yadro-project/internal/core/ports"
+)
+
+var ( //errors
+	ErrOverloaded       = errors.New("server is overloaded")
+	ErrQueueFull        = fmt.Errorf("%w: wait queue is full", ErrOverloaded)
+	ErrAdmissionTimeout = fmt.Errorf("%w: wait timed out", ErrOverloaded)
+)
+
+type AdmissionStats struct {
+	Limit             int    `json:"limit"`
+	Active            int    `json:"active"`
+	Queued            int    `json:"queued"`
+	RejectedQueueFull uint64 `json:"rejected_queue_full"`
+	RejectedTimeout   uint64 `json:"rejected_timeout"`
+}
+
+// Admission lets at most limit requests run at once. Other requests wait in
+// a FIFO queue of at most maxQueue entries for no longer than timeout.
+type Admission struct {
+	mu       sync.Mutex
+	limit    int
+	active   int
+	waiters  *list.List
+	maxQueue int
+	timeout  time.Duration
+	stats    AdmissionStats
+	metrics  ports.Metrics
+}
+
+func NewAdmission(limit, maxQueue int, timeout time.Duration, metrics ports.Metrics) *Admission {
+	return &Admission{
+		limit:    limit,
+		waiters:  list.New(),
+		maxQueue: maxQueue,
+		timeout:  timeout,
+		metrics:  orNoMetrics(metrics),
+	}
+}
+
+func (a *Admission) Acquire(ctx context.Context) error {
+	a.mu.Lock()
+	if a.active < a.limit && a.waiters.Len() == 0 {
+		a.active++
+		a.metrics.AddAdmissionActive(1)
+		a.mu.Unlock()
+		return nil
+	}
+	wait := a.timeout
+	if a.waiters.Len() >= a.maxQueue {
+		a.stats.RejectedQueueFull++
+		a.metrics.AdmissionRejected("queue_full")
+		a.mu.Unlock()
+		return ErrQueueFull
+	}
+	ready := make(chan struct{})
+	el := a.waiters.PushBack(ready)
+	a.metrics.AddAdmissionQueued(1)
+	a.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		err = ErrContextDone
+	case <-timeout:
+		err = ErrAdmissionTimeout
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	select {
+	case <-ready:
+		// the slot was handed over while we were giving up
+		return nil
+	default:
+	}
+	a.waiters.Remove(el)
+	a.metrics.AddAdmissionQueued(-1)
+	if errors.Is(err, ErrAdmissionTimeout) {
+		a.stats.RejectedTimeout++
+		a.metrics.AdmissionRejected("timeout")
+	}
+	return err
+}
+
+func (a *Admission) Release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.active <= a.limit {
+		if el := a.waiters.Front(); el != nil {
+			a.waiters.Remove(el)
+			a.metrics.AddAdmissionQueued(-1)
+			close(el.Value.(chan struct{}))
+			return
+		}
+	}
+	a.active--
+	a.metrics.AddAdmissionActive(-1)
+}
+
+// SetLimit changes the limit. Requests over a lowered limit keep running,
+// new ones wait until enough of them are released.
+func (a *Admission) SetLimit(limit int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.limit = limit
+	for a.active < a.limit {
+		el := a.waiters.Front()
+		if el == nil {
+			return
+		}
+		a.waiters.Remove(el)
+		a.metrics.AddAdmissionQueued(-1)
+		close(el.Value.(chan struct{}))
+		a.active++
+		a.metrics.AddAdmissionActive(1)
+	}
+}
+
+// SetQueue changes the queue size and the wait timeout. Requests already in
+// a longer queue stay there.
+func (a *Admission) SetQueue(maxQueue int, timeout time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.maxQueue = maxQueue
+	a.timeout = timeout
+}
+
+// RetryAfter is how long rejected clients are asked to wait.
+func (a *Admission) RetryAfter() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return max(a.timeout, time.Second)
+}
+
+func (a *Admission) Stats() AdmissionStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	stats := a.stats
+	stats.Limit = a.limit
+	stats.Active = a.active
+	stats.Queued = a.waiters.Len()
+	return stats
+}
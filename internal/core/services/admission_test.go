@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAdmission_QueueAndTimeout(t *testing.T) {
+	a := NewAdmission(1, 1, time.Millisecond*50, nil)
+	if err := a.Acquire(context.Background()); err != nil {
+		t.Fatalf("first acquire: unexpected error: %s", err)
+	}
+
+	queued := make(chan error)
+	go func() {
+		queued <- a.Acquire(context.Background())
+	}()
+	for a.Stats().Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := a.Acquire(context.Background()); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("got error %v, want %v", err, ErrQueueFull)
+	}
+	if err := <-queued; !errors.Is(err, ErrAdmissionTimeout) {
+		t.Fatalf("got error %v, want %v", err, ErrAdmissionTimeout)
+	}
+
+	stats := a.Stats()
+	if stats.Active != 1 || stats.Queued != 0 || stats.RejectedQueueFull != 1 || stats.RejectedTimeout != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestAdmission_HandOver(t *testing.T) {
+	a := NewAdmission(1, 10, time.Second, nil)
+	if err := a.Acquire(context.Background()); err != nil {
+		t.Fatalf("first acquire: unexpected error: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error)
+	go func() {
+		cancelled <- a.Acquire(ctx)
+	}()
+	queued := make(chan error)
+	go func() {
+		for a.Stats().Queued != 1 {
+			time.Sleep(time.Millisecond)
+		}
+		queued <- a.Acquire(context.Background())
+	}()
+	for a.Stats().Queued != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	if err := <-cancelled; !errors.Is(err, ErrContextDone) {
+		t.Fatalf("got error %v, want %v", err, ErrContextDone)
+	}
+	a.Release()
+	if err := <-queued; err != nil {
+		t.Fatalf("queued acquire: unexpected error: %s", err)
+	}
+	if stats := a.Stats(); stats.Active != 1 || stats.Queued != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+type AuditService struct {
+	repo ports.AuditRepository
+}
+
+func NewAuditService(repo ports.AuditRepository) *AuditService {
+	return &AuditService{
+		repo: repo,
+	}
+}
+
+// Record appends the event to the audit trail. A failure to write the trail
+// must not fail the audited action, so the error is only logged.
+func (svc *AuditService) Record(ctx context.Context, event domain.AuditEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if err := svc.repo.Append(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "error append audit event", "action", event.Action, "error", err)
+	}
+}
+
+func (svc *AuditService) Find(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	filter.Limit = min(filter.Limit, maxAuditLimit)
+	events, err := svc.repo.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error find audit events: %w", err)
+	}
+	return events, nil
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 	"yadro-project/internal/core/domain"
 	"yadro-project/internal/core/ports"
@@ -14,20 +15,22 @@ import (
 var (
 	ErrBadCredentials = errors.New("email or password is incorrect")
 	ErrTokenInvalid   = errors.New("token invalid")
+	ErrForbidden      = errors.New("access denied")
 )
 
 type AuthService struct {
 	repo         ports.AuthRepository
-	tokenMaxTime time.Duration
+	tokenMaxTime atomic.Int64
 	jwtSecretKey []byte
 }
 
 func NewAuthService(repo ports.AuthRepository, tokenMaxTime time.Duration) *AuthService {
-	return &AuthService{
+	svc := &AuthService{
 		repo:         repo,
-		tokenMaxTime: tokenMaxTime,
 		jwtSecretKey: []byte("sdgsfdghtrmogmfsdgskfdgnlsf"),
 	}
+	svc.SetTokenMaxTime(tokenMaxTime)
+	return svc
 }
 
 func (svc *AuthService) Login(request domain.LoginRequest) (string, error) {
@@ -45,7 +48,7 @@ func (svc *AuthService) Login(request domain.LoginRequest) (string, error) {
 	}
 	payload := jwt.MapClaims{
 		"sub": request.Email,
-		"exp": time.Now().Add(time.Minute * svc.tokenMaxTime).Unix(),
+		"exp": time.Now().Add(svc.TokenMaxTime()).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
@@ -58,27 +61,40 @@ func (svc *AuthService) Login(request domain.LoginRequest) (string, error) {
 	return t, nil
 }
 
-func (svc *AuthService) CheckToken(sToken string) (bool, error) {
+func (svc *AuthService) TokenMaxTime() time.Duration {
+	return time.Duration(svc.tokenMaxTime.Load())
+}
+
+// SetTokenMaxTime changes the lifetime of tokens issued from now on.
+func (svc *AuthService) SetTokenMaxTime(d time.Duration) {
+	svc.tokenMaxTime.Store(int64(d))
+}
+
+// CheckToken validates the token and returns the email of its user.
+func (svc *AuthService) CheckToken(sToken string) (string, error) {
 	email, err := svc.GetEmailFromToken(sToken)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 	if _, err = svc.repo.GetPasswordByEmail(email); err != nil {
-		return false, err
+		if errors.Is(err, ports.ErrIsNotExist) {
+			return "", ErrTokenInvalid
+		}
+		return "", fmt.Errorf("error check user: %w", err)
 	}
-	return true, nil
+	return email, nil
 }
 
 func (svc *AuthService) GetEmailFromToken(sToken string) (string, error) {
 	t, err := jwt.Parse(sToken, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return nil, nil
+		return svc.jwtSecretKey, nil
 	})
 
 	if err != nil {
-		return "", fmt.Errorf("error parse token: %w", err)
+		return "", fmt.Errorf("%w: %w", ErrTokenInvalid, err)
 	}
 
 	if !t.Valid {
@@ -91,3 +107,14 @@ func (svc *AuthService) GetEmailFromToken(sToken string) (string, error) {
 	}
 	return sub, nil
 }
+
+func (svc *AuthService) Role(email string) (string, error) {
+	isAdmin, err := svc.repo.CheckAdminByEmail(email)
+	if err != nil {
+		return "", fmt.Errorf("error check admin: %w", err)
+	}
+	if isAdmin {
+		return domain.RoleAdmin, nil
+	}
+	return domain.RoleUser, nil
+}
@@ -4,27 +4,51 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
+	"log/slog"
+	"slices"
+	"sync/atomic"
 	"time"
-	"unicode"
 	"yadro-project/internal/core/domain"
 	"yadro-project/internal/core/ports"
 	"yadro-project/pkg/pair"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ComicsService struct {
-	repo    ports.ComicsRepository
-	parser  ports.Parser
-	indexer ports.Indexer
-	stemmer ports.Stemmer
+	repo              ports.ComicsRepository
+	parser            ports.Parser
+	indexer           ports.Indexer
+	stemmer           ports.Stemmer
+	fullCheckInterval time.Duration
+	updating          atomic.Int32
+	cache             *searchCache
+	version           atomic.Pointer[cachedVersion]
+	metrics           ports.Metrics
+}
+
+// versionTTL bounds how long an update by another instance goes unnoticed.
+const versionTTL = time.Second
+
+type cachedVersion struct {
+	version domain.ComicsVersion
+	expires time.Time
 }
 
-func NewComicsService(repo ports.ComicsRepository, parser ports.Parser, indexer ports.Indexer, stemmer ports.Stemmer) *ComicsService {
+// NewComicsService creates the service. Every fullCheckInterval an update
+// fetches all comics again instead of only the missing ones. Up to cacheSize
+// search results are cached for cacheTTL or until the comics change.
+func NewComicsService(repo ports.ComicsRepository, parser ports.Parser, indexer ports.Indexer, stemmer ports.Stemmer, fullCheckInterval time.Duration, cacheSize int, cacheTTL time.Duration, metrics ports.Metrics) *ComicsService {
+	metrics = orNoMetrics(metrics)
 	return &ComicsService{
-		repo:    repo,
-		parser:  parser,
-		indexer: indexer,
-		stemmer: stemmer,
+		repo:              repo,
+		parser:            parser,
+		indexer:           indexer,
+		stemmer:           stemmer,
+		fullCheckInterval: fullCheckInterval,
+		cache:             newSearchCache(cacheSize, cacheTTL, metrics),
+		metrics:           metrics,
 	}
 }
 
@@ -32,22 +56,39 @@ var (
 	ErrContextDone = errors.New("server is not accepting new requests")
 )
 
-func (srv *ComicsService) GetComics(ctx context.Context, search string) ([]domain.Comics, error) {
+func (srv *ComicsService) GetComics(ctx context.Context, search string) (comics []domain.Comics, err error) {
+	ctx, span := tracer.Start(ctx, "ComicsService.GetComics")
+	defer endSpan(span, &err)
 	select {
 	case <-ctx.Done():
 		return nil, ErrContextDone
 	default:
 	}
-	stemmed, err := srv.stemmer.Stem(strings.FieldsFunc(search, func(r rune) bool {
-		return !unicode.IsLetter(r) && r != '\''
-	}))
+	stemmed, err := srv.stemmer.Stem(domain.SplitWords(search))
 	if err != nil {
 		return nil, err
 	}
-	comics, err := srv.searchComics(ctx, 10, stemmed)
+	span.SetAttributes(attribute.Int("search.keywords", len(stemmed)))
+	// without the version the search still falls back to the repository,
+	// its result is just not cached
+	version, verErr := srv.Version(ctx)
+	key := searchKey(10, stemmed)
+	if verErr == nil {
+		comics, ok := srv.cache.get(version, key)
+		span.SetAttributes(attribute.Bool("search.cached", ok))
+		if ok {
+			return comics, nil
+		}
+	}
+	comics, err = srv.searchComics(ctx, 10, stemmed)
 	if err != nil {
 		return nil, fmt.Errorf("error search comics: %w", err)
 	}
+	// a result computed after an update is stored for the older version,
+	// which is not looked up anymore
+	if verErr == nil {
+		srv.cache.put(version, key, comics)
+	}
 	return comics, nil
 }
 
@@ -57,8 +98,16 @@ func (srv *ComicsService) searchComics(ctx context.Context, n int, keywords []st
 		return nil, ErrContextDone
 	default:
 	}
+	ctx, span := tracer.Start(ctx, "ComicsService.searchComics")
+	start, source := time.Now(), "index"
+	defer func() {
+		srv.metrics.ObserveSearch(source, time.Since(start))
+		span.SetAttributes(attribute.String("search.source", source))
+		span.End()
+	}()
 	idxLastUpdate, err := srv.indexer.GetLastUpdateTime(ctx)
 	if err != nil {
+		source = "repository"
 		comics, err := srv.searchComicsFromRepository(ctx, n, keywords)
 		if err != nil {
 			return nil, fmt.Errorf("error search from repository: %w", err)
@@ -70,6 +119,7 @@ func (srv *ComicsService) searchComics(ctx context.Context, n int, keywords []st
 		return nil, fmt.Errorf("error get last update time repo: %w", err)
 	}
 	if !idxLastUpdate.Equal(repoLastUpdate) {
+		source = "repository"
 		comics, err := srv.searchComicsFromRepository(ctx, n, keywords)
 		if err != nil {
 			return nil, fmt.Errorf("erorr search from repository: %w", err)
@@ -78,6 +128,7 @@ func (srv *ComicsService) searchComics(ctx context.Context, n int, keywords []st
 	}
 	indexes, err := srv.indexer.GetNumbersOfNMostRelevantComics(ctx, n, keywords)
 	if err != nil {
+		source = "repository"
 		comics, err := srv.searchComicsFromRepository(ctx, n, keywords)
 		if err != nil {
 			return nil, fmt.Errorf("erorr search from repository: %w", err)
@@ -92,6 +143,8 @@ func (srv *ComicsService) searchComics(ctx context.Context, n int, keywords []st
 }
 
 func (srv *ComicsService) searchComicsFromRepository(ctx context.Context, n int, keywords []string) ([]domain.Comics, error) {
+	ctx, span := tracer.Start(ctx, "ComicsService.searchComicsFromRepository")
+	defer span.End()
 	base := make(map[string]bool, len(keywords))
 	for _, keyword := range keywords {
 		base[keyword] = true
@@ -103,10 +156,10 @@ func (srv *ComicsService) searchComicsFromRepository(ctx context.Context, n int,
 	}
 
 	k := make(map[int]int, len(comics))
-	for ID, c := range comics {
+	for _, c := range comics {
 		for _, keyword := range c.Keywords {
 			if base[keyword] {
-				k[ID]++
+				k[c.ID]++
 			}
 		}
 	}
@@ -118,6 +171,8 @@ func (srv *ComicsService) searchComicsFromRepository(ctx context.Context, n int,
 }
 
 func (srv *ComicsService) getComicsByIDs(ctx context.Context, indexes []int) ([]domain.Comics, error) {
+	ctx, span := tracer.Start(ctx, "ComicsService.getComicsByIDs", trace.WithAttributes(attribute.Int("comics.count", len(indexes))))
+	defer span.End()
 	ans := make([]domain.Comics, 0, len(indexes))
 	for _, ID := range indexes {
 		url, err := srv.repo.GetURLComicsByID(ctx, ID)
@@ -129,7 +184,56 @@ func (srv *ComicsService) getComicsByIDs(ctx context.Context, indexes []int) ([]
 	return ans, nil
 }
 
-func (srv *ComicsService) UpdateComics(ctx context.Context) (domain.UpdateMeta, error) {
+// Version returns what search results currently depend on, so clients can
+// revalidate them cheaply. It is read from the storage at most once per
+// versionTTL, and again right after this instance changes the comics.
+func (srv *ComicsService) Version(ctx context.Context) (domain.ComicsVersion, error) {
+	if v := srv.version.Load(); v != nil && time.Now().Before(v.expires) {
+		return v.version, nil
+	}
+	generation, err := srv.indexer.GetGeneration(ctx)
+	if err != nil {
+		return domain.ComicsVersion{}, fmt.Errorf("error get index generation: %w", err)
+	}
+	updatedAt, err := srv.repo.GetLastUpdateTime(ctx)
+	if err != nil {
+		return domain.ComicsVersion{}, fmt.Errorf("error get last update time repo: %w", err)
+	}
+	version := domain.ComicsVersion{Generation: generation, UpdatedAt: updatedAt}
+	srv.version.Store(&cachedVersion{version: version, expires: time.Now().Add(versionTTL)})
+	return version, nil
+}
+
+// changed drops what was cached for the previous version of the comics.
+func (srv *ComicsService) changed() {
+	srv.version.Store(nil)
+	srv.cache.invalidate()
+}
+
+// UpdateRunning reports whether an update or a reindex is running. Runs are
+// counted, so the first of two overlapping runs doesn't clear it when done.
+func (srv *ComicsService) UpdateRunning() bool {
+	return srv.updating.Load() > 0
+}
+
+func (srv *ComicsService) IndexIsFresh(ctx context.Context) (bool, error) {
+	idxLastUpdate, err := srv.indexer.GetLastUpdateTime(ctx)
+	if err != nil {
+		return false, fmt.Errorf("error get last update time index: %w", err)
+	}
+	repoLastUpdate, err := srv.repo.GetLastUpdateTime(ctx)
+	if err != nil {
+		return false, fmt.Errorf("error get last update time repo: %w", err)
+	}
+	return idxLastUpdate.Equal(repoLastUpdate), nil
+}
+
+func (srv *ComicsService) UpdateComics(ctx context.Context) (meta domain.UpdateMeta, err error) {
+	ctx, span := tracer.Start(ctx, "ComicsService.UpdateComics")
+	defer endSpan(span, &err)
+	srv.updating.Add(1)
+	defer srv.updating.Add(-1)
+
 	cntInServer, err := srv.parser.GetCountComicsInServer(ctx)
 	if err != nil {
 		return domain.UpdateMeta{}, fmt.Errorf("error get count of comics in server: %w", err)
@@ -145,14 +249,15 @@ func (srv *ComicsService) UpdateComics(ctx context.Context) (domain.UpdateMeta,
 		return domain.UpdateMeta{}, fmt.Errorf("error get last full check time in storage: %w", err)
 	}
 
-	if cnt == 0 || srv.checkMonth(t) {
+	start := time.Now()
+	full := cnt == 0 || srv.needFullCheck(t)
+	if full {
 		parsedComics, err = srv.parser.FullParse(ctx, cntInServer)
 		if err != nil {
 			return domain.UpdateMeta{}, fmt.Errorf("error full parse: %w", err)
 		}
-		if err = srv.repo.UpdateLastFullCheckTime(ctx, time.Now()); err != nil {
-			return domain.UpdateMeta{}, fmt.Errorf("error update last full check time: %w", err)
-		}
+		srv.metrics.ObserveCrawl("full", time.Since(start))
+		span.SetAttributes(attribute.String("update.mode", "full"))
 	} else {
 		isNotExists, err := srv.repo.GetIDMissingComics(ctx, cntInServer)
 		if err != nil {
@@ -162,71 +267,146 @@ func (srv *ComicsService) UpdateComics(ctx context.Context) (domain.UpdateMeta,
 		if err != nil {
 			return domain.UpdateMeta{}, fmt.Errorf("error part parse: %w", err)
 		}
+		srv.metrics.ObserveCrawl("part", time.Since(start))
+		span.SetAttributes(attribute.String("update.mode", "part"))
 	}
 
-	for _, comics := range parsedComics {
-		if err = srv.repo.Add(ctx, comics, comics.ID); err != nil {
-			return domain.UpdateMeta{}, fmt.Errorf("error add comics in storage: %w", err)
-		}
+	// comics, index and their update times change together or not at all
+	added, changed, err := srv.repo.Commit(ctx, parsedComics, full)
+	if err != nil {
+		return domain.UpdateMeta{}, fmt.Errorf("error commit comics in storage: %w", err)
 	}
-
-	if err = srv.updateIndex(ctx, parsedComics); err != nil {
-		return domain.UpdateMeta{}, fmt.Errorf("error update index: %w", err)
+	if changed {
+		srv.changed()
 	}
 
-	var updateTime time.Time
-	if len(parsedComics) == 0 {
-		updateTime, err = srv.repo.GetLastUpdateTime(ctx)
-		if err != nil {
-			return domain.UpdateMeta{}, fmt.Errorf("error get last update time from repository: %w", err)
-		}
-	} else {
-		updateTime = time.Now()
+	span.SetAttributes(attribute.Int("update.new", added))
+	return domain.UpdateMeta{
+		New:   added,
+		Total: added + cnt,
+	}, nil
+}
+
+func (srv *ComicsService) CheckConsistency(ctx context.Context) (report domain.ConsistencyReport, err error) {
+	ctx, span := tracer.Start(ctx, "ComicsService.CheckConsistency")
+	defer endSpan(span, &err)
+	report, err = srv.repo.CheckConsistency(ctx)
+	if err != nil {
+		return domain.ConsistencyReport{}, fmt.Errorf("error check consistency: %w", err)
 	}
+	span.SetAttributes(attribute.Bool("consistency.ok", report.Consistent()))
+	return report, nil
+}
 
-	if err = srv.repo.Close(ctx, updateTime); err != nil {
-		return domain.UpdateMeta{}, fmt.Errorf("error save comics in storage: %w", err)
+// RepairConsistency reindexes only the comics the check reports, or the
+// whole index if it is stale. Comics stored without keywords are fetched
+// again first. The returned report is
+// the one found before the repair.
+func (srv *ComicsService) RepairConsistency(ctx context.Context) (report domain.ConsistencyReport, err error) {
+	ctx, span := tracer.Start(ctx, "ComicsService.RepairConsistency")
+	defer endSpan(span, &err)
+	report, err = srv.repo.CheckConsistency(ctx)
+	if err != nil {
+		return domain.ConsistencyReport{}, fmt.Errorf("error check consistency: %w", err)
 	}
 
-	if err = srv.indexer.Save(ctx, updateTime); err != nil {
-		return domain.UpdateMeta{}, fmt.Errorf("error save comics in storage: %w", err)
+	fetched, err := srv.parser.PartParse(ctx, report.EmptyComics)
+	if err != nil {
+		return domain.ConsistencyReport{}, fmt.Errorf("error fetch comics without keywords: %w", err)
+	}
+	affected := make(map[int]domain.Comics, len(fetched)+len(report.MissingPostings)+len(report.OrphanPostings))
+	for _, id := range slices.Concat(report.MissingPostings, report.OrphanPostings) {
+		affected[id] = domain.Comics{ID: id}
+	}
+	for _, c := range fetched {
+		affected[c.ID] = c
+	}
+	if len(affected) == 0 && len(report.OrphanKeywords) == 0 && report.IndexFresh {
+		return report, nil
 	}
 
-	return domain.UpdateMeta{
-		New:   len(parsedComics),
-		Total: len(parsedComics) + cnt,
-	}, nil
+	comics := make([]domain.Comics, 0, len(affected))
+	report.Repaired = make([]int, 0, len(affected))
+	for id, c := range affected {
+		comics = append(comics, c)
+		report.Repaired = append(report.Repaired, id)
+	}
+	slices.Sort(report.Repaired)
+	if err = srv.repo.RepairIndex(ctx, comics); err != nil {
+		return domain.ConsistencyReport{}, fmt.Errorf("error reindex comics: %w", err)
+	}
+	srv.changed()
+	span.SetAttributes(attribute.Int("consistency.repaired", len(report.Repaired)))
+	return report, nil
 }
 
-func (srv *ComicsService) updateIndex(ctx context.Context, parsedComics []domain.Comics) error {
-	idxTime, err := srv.indexer.GetLastUpdateTime(ctx)
-	if err != nil {
-		return fmt.Errorf("error get last update of index: %w", err)
-	}
+// ReindexComics stems the stored transcripts and alt texts again and switches
+// searches to the new index once it is built. Comics stored before their text
+// was kept are fetched again; those that can't be fetched keep their stored
+// keywords.
+func (srv *ComicsService) ReindexComics(ctx context.Context) (meta domain.ReindexMeta, err error) {
+	ctx, span := tracer.Start(ctx, "ComicsService.ReindexComics")
+	defer endSpan(span, &err)
+	srv.updating.Add(1)
+	defer srv.updating.Add(-1)
 
-	repoTime, err := srv.repo.GetLastUpdateTime(ctx)
+	comics, err := srv.repo.GetComicsText(ctx)
 	if err != nil {
-		return fmt.Errorf("error get last update time of repository: %w", err)
+		return domain.ReindexMeta{}, fmt.Errorf("error get comics text: %w", err)
 	}
-
-	if idxTime != repoTime {
-		parsedComics, err = srv.repo.GetComics(ctx)
+	missing := make([]int, 0)
+	for _, c := range comics {
+		if c.Transcript == "" && c.Alt == "" {
+			missing = append(missing, c.ID)
+		}
+	}
+	texts := make(map[int]domain.Comics)
+	if len(missing) > 0 {
+		fetched, err := srv.parser.PartParse(ctx, missing)
 		if err != nil {
-			return fmt.Errorf("error get comics from repository: %w", err)
+			return domain.ReindexMeta{}, fmt.Errorf("error fetch comics without text: %w", err)
+		}
+		if err = srv.repo.SaveText(ctx, fetched); err != nil {
+			return domain.ReindexMeta{}, fmt.Errorf("error save comics text: %w", err)
 		}
-		if err = srv.indexer.Clear(ctx); err != nil {
-			return fmt.Errorf("error clear index: %w", err)
+		for _, c := range fetched {
+			texts[c.ID] = c
+		}
+		meta.Fetched = len(fetched)
+		meta.Skipped = len(missing) - len(fetched)
+		if meta.Skipped > 0 {
+			slog.WarnContext(ctx, "comics could not be fetched, keeping their keywords", "count", meta.Skipped)
 		}
 	}
 
-	for _, comics := range parsedComics {
-		if err = srv.indexer.UpdateIndex(ctx, comics.ID, comics.Keywords); err != nil {
-			return fmt.Errorf("error update index: %w", err)
+	// without text the stemmer finds nothing, so only comics with text are
+	// built and the rest keep their stored keywords
+	built := make([]domain.Comics, 0, len(comics))
+	for _, c := range comics {
+		if c.Transcript == "" && c.Alt == "" {
+			f, ok := texts[c.ID]
+			if !ok {
+				continue
+			}
+			c.Transcript, c.Alt = f.Transcript, f.Alt
 		}
+		if c.Keywords, err = srv.stemmer.Stem(c.Words()); err != nil {
+			return domain.ReindexMeta{}, fmt.Errorf("error stem comics %d: %w", c.ID, err)
+		}
+		built = append(built, c)
+	}
+	if meta.Generation, err = srv.repo.BuildIndex(ctx, built); err != nil {
+		return domain.ReindexMeta{}, fmt.Errorf("error build index: %w", err)
+	}
+	if err = srv.repo.SwitchIndex(ctx, meta.Generation); err != nil {
+		return domain.ReindexMeta{}, fmt.Errorf("error switch index: %w", err)
 	}
-	return nil
+	srv.changed()
+	meta.Comics = len(built)
+	span.SetAttributes(attribute.Int("reindex.generation", meta.Generation), attribute.Int("reindex.comics", meta.Comics))
+	return meta, nil
 }
 
-func (srv *ComicsService) checkMonth(t time.Time) bool {
-	return t.AddDate(0, 1, 0).Before(time.Now())
+func (srv *ComicsService) needFullCheck(t time.Time) bool {
+	return t.Add(srv.fullCheckInterval).Before(time.Now())
 }
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+)
+
+// reindexRepo records the comics passed to the build.
+type reindexRepo struct {
+	ports.ComicsRepository
+	comics   []domain.Comics
+	built    []domain.Comics
+	switched int
+}
+
+func (r *reindexRepo) GetComicsText(ctx context.Context) ([]domain.Comics, error) {
+	return r.comics, nil
+}
+
+func (r *reindexRepo) SaveText(ctx context.Context, comics []domain.Comics) error {
+	return nil
+}
+
+func (r *reindexRepo) BuildIndex(ctx context.Context, comics []domain.Comics) (int, error) {
+	r.built = comics
+	return 2, nil
+}
+
+func (r *reindexRepo) SwitchIndex(ctx context.Context, generation int) error {
+	r.switched = generation
+	return nil
+}
+
+// flakyParser fetches only the comics in texts, like PartParse skipping
+// failed requests.
+type flakyParser struct {
+	ports.Parser
+	texts map[int]string
+}
+
+func (p flakyParser) PartParse(ctx context.Context, IDs []int) ([]domain.Comics, error) {
+	comics := make([]domain.Comics, 0, len(IDs))
+	for _, id := range IDs {
+		if text, ok := p.texts[id]; ok {
+			comics = append(comics, domain.Comics{ID: id, Alt: text})
+		}
+	}
+	return comics, nil
+}
+
+type lowerStemmer struct{}
+
+func (lowerStemmer) Stem(words []string) ([]string, error) {
+	stems := make([]string, 0, len(words))
+	for _, w := range words {
+		stems = append(stems, strings.ToLower(w))
+	}
+	return stems, nil
+}
+
+func TestComicsService_ReindexKeepsUnfetchedComics(t *testing.T) {
+	repo := &reindexRepo{comics: []domain.Comics{
+		{ID: 1, Transcript: "Python"},
+		{ID: 2},
+		{ID: 3},
+	}}
+	parser := flakyParser{texts: map[int]string{2: "Tree"}}
+	svc := NewComicsService(repo, parser, nil, lowerStemmer{}, time.Hour, 0, 0, nil)
+
+	meta, err := svc.ReindexComics(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if meta.Fetched != 1 || meta.Skipped != 1 || meta.Comics != 2 || meta.Generation != 2 {
+		t.Fatalf("got meta %+v, want 1 fetched, 1 skipped, 2 comics of generation 2", meta)
+	}
+	if repo.switched != 2 {
+		t.Fatalf("switched to generation %d, want 2", repo.switched)
+	}
+	got := make(map[int][]string, len(repo.built))
+	for _, c := range repo.built {
+		got[c.ID] = c.Keywords
+	}
+	if _, ok := got[3]; ok {
+		t.Fatal("comics 3 was not fetched and must keep its stored keywords")
+	}
+	if len(got[1]) != 1 || got[1][0] != "python" || len(got[2]) != 1 || got[2][0] != "tree" {
+		t.Fatalf("got built keywords %v, want python for 1 and tree for 2", got)
+	}
+}
+
+// blockedRepo holds builds until release is closed.
+type blockedRepo struct {
+	reindexRepo
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (r *blockedRepo) BuildIndex(ctx context.Context, comics []domain.Comics) (int, error) {
+	r.entered <- struct{}{}
+	<-r.release
+	return 1, nil
+}
+
+func (r *blockedRepo) SwitchIndex(ctx context.Context, generation int) error {
+	return nil
+}
+
+func TestComicsService_UpdateRunningWhileRunsOverlap(t *testing.T) {
+	repo := &blockedRepo{entered: make(chan struct{}), release: make(chan struct{})}
+	svc := NewComicsService(repo, nil, nil, lowerStemmer{}, time.Hour, 0, 0, nil)
+
+	first := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		svc.ReindexComics(context.Background())
+		close(first)
+	}()
+	<-repo.entered
+	go func() {
+		svc.ReindexComics(context.Background())
+		close(done)
+	}()
+	<-repo.entered
+
+	repo.release <- struct{}{}
+	<-first
+	if !svc.UpdateRunning() {
+		t.Fatal("the first run to finish cleared the flag of the other")
+	}
+	close(repo.release)
+	<-done
+	if svc.UpdateRunning() {
+		t.Fatal("update is running after both runs finished")
+	}
+}
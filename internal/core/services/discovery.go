@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DiscoveryService serves comics picked from the stored corpus rather than
+// by search: a random one and the comics of the day.
+type DiscoveryService struct {
+	repo     ports.ComicsRepository
+	daily    ports.DailyRepository
+	stemmer  ports.Stemmer
+	noRepeat int
+	now      func() time.Time
+}
+
+// NewDiscoveryService creates the service. The comics of the day is not
+// repeated within noRepeat days, as long as the corpus is large enough.
+func NewDiscoveryService(repo ports.ComicsRepository, daily ports.DailyRepository, stemmer ports.Stemmer, noRepeat int) *DiscoveryService {
+	return &DiscoveryService{
+		repo:     repo,
+		daily:    daily,
+		stemmer:  stemmer,
+		noRepeat: noRepeat,
+		now:      time.Now,
+	}
+}
+
+// Random returns a random comics having every keyword of the phrase after
+// stemming. An empty phrase matches every comics.
+func (srv *DiscoveryService) Random(ctx context.Context, keywords string) (c domain.ComicsInfo, err error) {
+	ctx, span := tracer.Start(ctx, "DiscoveryService.Random")
+	defer endSpan(span, &err)
+	stemmed, err := srv.stemmer.Stem(domain.SplitWords(keywords))
+	if err != nil {
+		return domain.ComicsInfo{}, err
+	}
+	stemmed = slices.Clone(stemmed)
+	slices.Sort(stemmed)
+	stemmed = slices.Compact(stemmed)
+	span.SetAttributes(attribute.Int("search.keywords", len(stemmed)))
+	c, err = srv.repo.GetRandomComics(ctx, stemmed)
+	if err != nil {
+		return domain.ComicsInfo{}, fmt.Errorf("error get random comics: %w", err)
+	}
+	return c, nil
+}
+
+// Today returns the comics of the current UTC date. The first request of a
+// day chooses it from a hash of the date, so instances choosing at once
+// agree, and the choice is stored.
+func (srv *DiscoveryService) Today(ctx context.Context) (daily domain.DailyComics, err error) {
+	ctx, span := tracer.Start(ctx, "DiscoveryService.Today")
+	defer endSpan(span, &err)
+	day := srv.now().UTC().Truncate(24 * time.Hour)
+	daily, err = srv.daily.Get(ctx, day)
+	if err == nil {
+		return daily, nil
+	}
+	if !errors.Is(err, ports.ErrIsNotExist) {
+		return domain.DailyComics{}, fmt.Errorf("error get comics of the day: %w", err)
+	}
+
+	candidates, err := srv.daily.Candidates(ctx, day, srv.noRepeat)
+	if err != nil {
+		return domain.DailyComics{}, fmt.Errorf("error get candidates: %w", err)
+	}
+	if len(candidates) == 0 {
+		// the window is longer than the corpus, allow repeats
+		if candidates, err = srv.daily.Candidates(ctx, day, 0); err != nil {
+			return domain.DailyComics{}, fmt.Errorf("error get candidates: %w", err)
+		}
+	}
+	if len(candidates) == 0 {
+		return domain.DailyComics{}, ports.ErrIsNotExist
+	}
+	span.SetAttributes(attribute.Int("daily.candidates", len(candidates)))
+	daily, err = srv.daily.Save(ctx, day, pickDaily(day, candidates))
+	if err != nil {
+		return domain.DailyComics{}, fmt.Errorf("error save comics of the day: %w", err)
+	}
+	return daily, nil
+}
+
+// pickDaily picks one of the candidates, sorted ascending, by the date.
+func pickDaily(day time.Time, candidates []int) int {
+	h := fnv.New64a()
+	h.Write([]byte(day.Format(time.DateOnly)))
+	return candidates[h.Sum64()%uint64(len(candidates))]
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+)
+
+// fakeDaily stores the comics of the day of three comics.
+type fakeDaily struct {
+	days map[time.Time]int
+}
+
+func (d *fakeDaily) Get(ctx context.Context, day time.Time) (domain.DailyComics, error) {
+	id, ok := d.days[day]
+	if !ok {
+		return domain.DailyComics{}, ports.ErrIsNotExist
+	}
+	return domain.DailyComics{Date: day.Format(time.DateOnly), ComicsInfo: domain.ComicsInfo{ID: id}}, nil
+}
+
+func (d *fakeDaily) Candidates(ctx context.Context, day time.Time, days int) ([]int, error) {
+	var ids []int
+	for _, id := range []int{1, 2, 3} {
+		recent := false
+		for d, used := range d.days {
+			if used == id && d.Before(day) && !d.Before(day.AddDate(0, 0, -days)) {
+				recent = true
+			}
+		}
+		if !recent {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (d *fakeDaily) Save(ctx context.Context, day time.Time, ID int) (domain.DailyComics, error) {
+	if _, ok := d.days[day]; !ok {
+		d.days[day] = ID
+	}
+	return d.Get(ctx, day)
+}
+
+func TestDiscoveryService_Today(t *testing.T) {
+	daily := &fakeDaily{days: make(map[time.Time]int)}
+	srv := NewDiscoveryService(nil, daily, nil, 2)
+	start := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	var got []int
+	for i := range 3 {
+		srv.now = func() time.Time { return start.AddDate(0, 0, i).Add(15 * time.Hour) }
+		first, err := srv.Today(context.Background())
+		if err != nil {
+			t.Fatalf("day %d: %v", i, err)
+		}
+		again, err := srv.Today(context.Background())
+		if err != nil {
+			t.Fatalf("day %d: %v", i, err)
+		}
+		if again != first {
+			t.Errorf("day %d: got %+v, then %+v", i, first, again)
+		}
+		if first.Date != start.AddDate(0, 0, i).Format(time.DateOnly) {
+			t.Errorf("day %d: got date %s", i, first.Date)
+		}
+		got = append(got, first.ID)
+	}
+	// the window of 2 days leaves a single candidate on the third day
+	slices.Sort(got)
+	if !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("got comics %v repeated within the window", got)
+	}
+
+	// another instance with the same stored days agrees
+	other := NewDiscoveryService(nil, &fakeDaily{days: make(map[time.Time]int)}, nil, 2)
+	other.now = func() time.Time { return start }
+	want, _ := daily.Get(context.Background(), start)
+	if d, err := other.Today(context.Background()); err != nil || d.ID != want.ID {
+		t.Errorf("got %+v, %v from a fresh instance, want comics %d", d, err, want.ID)
+	}
+}
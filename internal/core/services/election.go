@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+)
+
+var ( //errors
+	ErrNotLeader = errors.New("another instance is running updates")
+)
+
+const (
+	updateLeaseName = "update"
+)
+
+// ElectionService elects the instance that runs updates when several
+// replicas share the database. The leader renews its lease every third of the
+// ttl, so it keeps the lease through two failed renewals in a row. Each
+// renewal waits at most a third of the ttl, and the leader steps down once
+// the lease may have expired even if a renewal is still running.
+type ElectionService struct {
+	lease    ports.LeaseRepository
+	instance string
+	ttl      time.Duration
+
+	mu         sync.Mutex
+	term       context.Context
+	cancel     context.CancelFunc
+	validUntil time.Time
+	expiry     *time.Timer
+}
+
+func NewElectionService(lease ports.LeaseRepository, instance string, ttl time.Duration) *ElectionService {
+	return &ElectionService{
+		lease:    lease,
+		instance: instance,
+		ttl:      ttl,
+	}
+}
+
+func (svc *ElectionService) Instance() string {
+	return svc.instance
+}
+
+// Run campaigns until ctx is done and then gives the lease up.
+func (svc *ElectionService) Run(ctx context.Context) {
+	ticker := time.NewTicker(svc.ttl / 3)
+	defer ticker.Stop()
+	for {
+		svc.Campaign(ctx)
+		select {
+		case <-ctx.Done():
+			svc.stepDown()
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), svc.ttl/3)
+			defer cancel()
+			if err := svc.lease.Release(ctx, updateLeaseName, svc.instance); err != nil {
+				slog.ErrorContext(ctx, "error release update lease", "error", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Campaign takes or renews the lease once.
+func (svc *ElectionService) Campaign(ctx context.Context) {
+	// the lease expires ttl after the database got the request at the
+	// latest, so it surely holds until ttl after we sent it
+	start := time.Now()
+	acquireCtx, cancel := context.WithTimeout(ctx, svc.ttl/3)
+	defer cancel()
+	ok, err := svc.lease.Acquire(acquireCtx, updateLeaseName, svc.instance, svc.ttl)
+	if err != nil {
+		// without a renewal the lease may expire at any moment
+		slog.ErrorContext(ctx, "error acquire update lease", "error", err)
+		svc.stepDown()
+		return
+	}
+	if !ok {
+		svc.stepDown()
+		return
+	}
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	svc.validUntil = start.Add(svc.ttl)
+	if !time.Now().Before(svc.validUntil) {
+		svc.stepDownLocked()
+		return
+	}
+	if svc.term == nil {
+		svc.term, svc.cancel = context.WithCancel(context.Background())
+		slog.InfoContext(ctx, "became update leader", "instance", svc.instance)
+	}
+	if svc.expiry == nil {
+		svc.expiry = time.AfterFunc(time.Until(svc.validUntil), svc.expire)
+	} else {
+		svc.expiry.Reset(time.Until(svc.validUntil))
+	}
+}
+
+// expire steps down if the lease was not renewed in time.
+func (svc *ElectionService) expire() {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	svc.leaderLocked()
+}
+
+func (svc *ElectionService) stepDown() {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	svc.stepDownLocked()
+}
+
+func (svc *ElectionService) stepDownLocked() {
+	if svc.term != nil {
+		svc.cancel()
+		svc.term, svc.cancel = nil, nil
+		slog.Info("lost update leadership", "instance", svc.instance)
+	}
+}
+
+// leaderLocked returns the current term, stepping down first if the lease
+// may have expired.
+func (svc *ElectionService) leaderLocked() context.Context {
+	if svc.term != nil && !time.Now().Before(svc.validUntil) {
+		svc.stepDownLocked()
+	}
+	return svc.term
+}
+
+func (svc *ElectionService) IsLeader() bool {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	return svc.leaderLocked() != nil
+}
+
+// Lead returns a context that is canceled when ctx is done or the instance
+// loses leadership, so an update stops before another leader starts one.
+func (svc *ElectionService) Lead(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	svc.mu.Lock()
+	term := svc.leaderLocked()
+	svc.mu.Unlock()
+	if term == nil {
+		return nil, nil, ErrNotLeader
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(term, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}, nil
+}
+
+// Leader returns the lease of the current leader.
+func (svc *ElectionService) Leader(ctx context.Context) (domain.Lease, error) {
+	l, err := svc.lease.Get(ctx, updateLeaseName)
+	if err != nil {
+		return domain.Lease{}, fmt.Errorf("error get update lease: %w", err)
+	}
+	return l, nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"yadro-project/internal/core/domain"
+)
+
+type stubLease struct {
+	ok    bool
+	err   error
+	block bool
+}
+
+func (l *stubLease) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	if l.block {
+		<-ctx.Done()
+		return false, ctx.Err()
+	}
+	return l.ok, l.err
+}
+
+func (l *stubLease) Get(ctx context.Context, name string) (domain.Lease, error) {
+	return domain.Lease{Name: name}, nil
+}
+
+func (l *stubLease) Release(ctx context.Context, name, holder string) error {
+	return nil
+}
+
+func TestElectionService_LosingLeaseCancelsUpdate(t *testing.T) {
+	lease := &stubLease{}
+	svc := NewElectionService(lease, "test", time.Minute)
+	svc.Campaign(context.Background())
+	if _, _, err := svc.Lead(context.Background()); !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("got %v, want %v", err, ErrNotLeader)
+	}
+
+	lease.ok = true
+	svc.Campaign(context.Background())
+	ctx, cancel, err := svc.Lead(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	lease.ok, lease.err = false, errors.New("connection refused")
+	svc.Campaign(context.Background())
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("update context is not canceled after the lease is lost")
+	}
+	if svc.IsLeader() {
+		t.Error("still the leader after a failed renewal")
+	}
+}
+
+func TestElectionService_HangingRenewal(t *testing.T) {
+	lease := &stubLease{ok: true}
+	ttl := 300 * time.Millisecond
+	svc := NewElectionService(lease, "test", ttl)
+	svc.Campaign(context.Background())
+	ctx, cancel, err := svc.Lead(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	lease.block = true
+	start := time.Now()
+	svc.Campaign(context.Background())
+	if d := time.Since(start); d > ttl/2 {
+		t.Errorf("renewal took %s, want it to give up after a third of the ttl", d)
+	}
+	if svc.IsLeader() {
+		t.Error("still the leader after a hanging renewal")
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Error("update context is not canceled after a hanging renewal")
+	}
+}
+
+func TestElectionService_LeaseExpires(t *testing.T) {
+	ttl := 100 * time.Millisecond
+	svc := NewElectionService(&stubLease{ok: true}, "test", ttl)
+	svc.Campaign(context.Background())
+	ctx, cancel, err := svc.Lead(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	// no renewal comes, e.g. the campaign loop is stuck
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("update context is not canceled after the lease expired")
+	}
+	if svc.IsLeader() {
+		t.Error("still the leader after the lease expired")
+	}
+	if _, _, err := svc.Lead(context.Background()); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("got %v, want %v", err, ErrNotLeader)
+	}
+}
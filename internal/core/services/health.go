@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+)
+
+type HealthService struct {
+	pingers  map[string]ports.Pinger
+	comics   *ComicsService
+	draining atomic.Bool
+}
+
+func NewHealthService(pingers map[string]ports.Pinger, comics *ComicsService) *HealthService {
+	return &HealthService{
+		pingers: pingers,
+		comics:  comics,
+	}
+}
+
+// Drain makes the server report itself not ready, so load balancers stop
+// sending requests before it shuts down.
+func (svc *HealthService) Drain() {
+	svc.draining.Store(true)
+}
+
+// Ready pings the dependencies and checks that the index is fresh. A stale
+// index only degrades the server, searches fall back to the repository, so
+// replicas are not all taken out of the load balancer at once.
+func (svc *HealthService) Ready(ctx context.Context) domain.Readiness {
+	r := domain.Readiness{
+		Status:        domain.HealthStatusReady,
+		UpdateRunning: svc.comics.UpdateRunning(),
+		Checks:        make([]domain.HealthCheck, 0, len(svc.pingers)+1),
+	}
+	for name, p := range svc.pingers {
+		check := domain.HealthCheck{Name: name, Status: domain.HealthStatusOK}
+		if err := p.Ping(ctx); err != nil {
+			slog.WarnContext(ctx, "readiness check failed", "check", name, "error", err)
+			check.Status = domain.HealthStatusFailed
+			r.Status = domain.HealthStatusNotReady
+		}
+		r.Checks = append(r.Checks, check)
+	}
+
+	check := domain.HealthCheck{Name: "index_fresh", Status: domain.HealthStatusOK}
+	fresh, err := svc.comics.IndexIsFresh(ctx)
+	switch {
+	case err != nil:
+		slog.WarnContext(ctx, "readiness check failed", "check", check.Name, "error", err)
+		check.Status = domain.HealthStatusFailed
+		r.Status = domain.HealthStatusNotReady
+	case !fresh:
+		check.Status = domain.HealthStatusStale
+		r.Degraded = true
+	}
+	r.Checks = append(r.Checks, check)
+
+	if svc.draining.Load() {
+		r.Status = domain.HealthStatusDraining
+	}
+	return r
+}
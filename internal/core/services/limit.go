@@ -1,62 +1,110 @@
 package services
 
 import (
+	"context"
 	"errors"
-
-	"golang.org/x/time/rate"
+	"log/slog"
+	"sync"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
 )
 
 var ( //errors
 	ErrManyRequests = errors.New("many requests")
 )
 
-type Semaphore struct {
-	ch chan struct{}
+const ( // policy keys
+	PolicyKeyUser = "user"
+	PolicyKeyIP   = "ip"
+)
+
+// RoutePolicy allows Limit requests per Per to Route for every user or IP,
+// depending on Key. An empty Role matches any role.
+type RoutePolicy struct {
+	Route string
+	Role  string
+	Key   string
+	Limit int
+	Per   time.Duration
+	Burst int
 }
 
-func (s *Semaphore) Acquire() {
-	s.ch <- struct{}{}
+func (p RoutePolicy) matches(route, role string) bool {
+	return p.Route == route && (p.Role == "" || p.Role == role)
 }
 
-func (s *Semaphore) Release() {
-	<-s.ch
+type LimitService struct {
+	mu        sync.RWMutex
+	policies  []RoutePolicy
+	limiter   ports.RateLimiter
+	admission *Admission
 }
 
-func NewSemaphore(limit int) Semaphore {
-	return Semaphore{
-		ch: make(chan struct{}, limit),
+func NewLimitService(policies []RoutePolicy, limiter ports.RateLimiter, admission *Admission) *LimitService {
+	return &LimitService{
+		policies:  policies,
+		limiter:   limiter,
+		admission: admission,
 	}
 }
 
-type LimitService struct {
-	rl        map[string]*rate.Limiter
-	rateLimit int
-	cl        Semaphore
+// Policy returns the first policy matching the route and role.
+func (svc *LimitService) Policy(route, role string) (RoutePolicy, bool) {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+	for _, p := range svc.policies {
+		if p.matches(route, role) {
+			return p, true
+		}
+	}
+	return RoutePolicy{}, false
 }
 
-func NewLimitService(rateLimit, concurrencyLimit int) *LimitService {
-	return &LimitService{
-		rl:        make(map[string]*rate.Limiter),
-		rateLimit: rateLimit,
-		cl:        NewSemaphore(concurrencyLimit),
-	}
+// SetPolicies replaces the policies. Buckets are keyed by route, role and
+// subject, so tokens already taken keep counting against the new limits.
+func (svc *LimitService) SetPolicies(policies []RoutePolicy) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	svc.policies = policies
+}
+
+func (svc *LimitService) SetConcurrency(limit, maxQueue int, timeout time.Duration) {
+	svc.admission.SetLimit(limit)
+	svc.admission.SetQueue(maxQueue, timeout)
 }
 
-func (svc *LimitService) Limit(email string) (*rate.Reservation, error) {
-	if _, ok := svc.rl[email]; !ok {
-		svc.rl[email] = rate.NewLimiter(rate.Limit(svc.rateLimit), 10)
+// Allow takes one token from the bucket of the subject. Requests to routes
+// without a policy are always allowed and get a zero status. If the limiter
+// fails, the request is allowed too: an unavailable limiter must not take the
+// whole API down.
+func (svc *LimitService) Allow(ctx context.Context, route, role, subject string) (domain.RateLimitStatus, error) {
+	p, ok := svc.Policy(route, role)
+	if !ok {
+		return domain.RateLimitStatus{}, nil
 	}
 
-	if !svc.rl[email].Allow() {
-		return nil, ErrManyRequests
+	rule := domain.RateLimitRule{Limit: p.Limit, Per: p.Per, Burst: p.Burst}
+	key := p.Route + "|" + p.Role + "|" + subject
+	status, err := svc.limiter.Take(ctx, key, rule)
+	if err != nil {
+		if errors.Is(err, ports.ErrLimitExceeded) {
+			return status, ErrManyRequests
+		}
+		slog.ErrorContext(ctx, "error take rate limit token", "key", key, "error", err)
+		return domain.RateLimitStatus{Limit: p.Limit, Remaining: p.Limit}, nil
 	}
+	return status, nil
+}
+
+func (svc *LimitService) Acquire(ctx context.Context) error {
+	return svc.admission.Acquire(ctx)
+}
 
-	r := svc.rl[email].Reserve()
-	svc.cl.Acquire()
-	return r, nil
+func (svc *LimitService) Release() {
+	svc.admission.Release()
 }
 
-func (svc *LimitService) Done(r *rate.Reservation) {
-	r.Cancel()
-	svc.cl.Release()
+func (svc *LimitService) RetryAfter() time.Duration {
+	return svc.admission.RetryAfter()
 }
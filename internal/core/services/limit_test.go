@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+)
+
+type ruleLimiter struct {
+	keys  []string
+	rules []domain.RateLimitRule
+	err   error
+}
+
+func (l *ruleLimiter) Take(ctx context.Context, key string, rule domain.RateLimitRule) (domain.RateLimitStatus, error) {
+	l.keys = append(l.keys, key)
+	l.rules = append(l.rules, rule)
+	return domain.RateLimitStatus{Limit: rule.Limit}, l.err
+}
+
+func TestLimitService_PolicyByRole(t *testing.T) {
+	l := &ruleLimiter{}
+	svc := NewLimitService([]RoutePolicy{
+		{Route: "GET /pics", Role: domain.RoleAdmin, Limit: 2, Per: time.Minute},
+		{Route: "GET /pics", Limit: 1, Per: time.Minute},
+	}, l, NewAdmission(10, 0, 0, nil))
+
+	if _, err := svc.Allow(context.Background(), "GET /pics", domain.RoleAdmin, "admin@test.com"); err != nil {
+		t.Fatalf("admin: unexpected error: %s", err)
+	}
+	if _, err := svc.Allow(context.Background(), "GET /pics", domain.RoleUser, "user@test.com"); err != nil {
+		t.Fatalf("user: unexpected error: %s", err)
+	}
+	status, err := svc.Allow(context.Background(), "POST /update", domain.RoleUser, "user@test.com")
+	if err != nil || status.Limit != 0 {
+		t.Fatalf("route without policy: got limit %d and error %v, want no limit", status.Limit, err)
+	}
+
+	if len(l.rules) != 2 || l.rules[0].Limit != 2 || l.rules[1].Limit != 1 {
+		t.Fatalf("got rules %v, want limits 2 and 1", l.rules)
+	}
+	if l.keys[0] == l.keys[1] {
+		t.Fatalf("admin and user share bucket %q", l.keys[0])
+	}
+}
+
+func TestLimitService_Errors(t *testing.T) {
+	policies := []RoutePolicy{{Route: "GET /pics", Limit: 1, Per: time.Minute}}
+
+	svc := NewLimitService(policies, &ruleLimiter{err: ports.ErrLimitExceeded}, NewAdmission(10, 0, 0, nil))
+	if _, err := svc.Allow(context.Background(), "GET /pics", domain.RoleUser, "user@test.com"); !errors.Is(err, ErrManyRequests) {
+		t.Fatalf("got error %v, want %v", err, ErrManyRequests)
+	}
+
+	svc = NewLimitService(policies, &ruleLimiter{err: errors.New("connection refused")}, NewAdmission(10, 0, 0, nil))
+	if _, err := svc.Allow(context.Background(), "GET /pics", domain.RoleUser, "user@test.com"); err != nil {
+		t.Fatalf("limiter failure: got error %v, want request allowed", err)
+	}
+}
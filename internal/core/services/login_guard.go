@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+)
+
+var ( //errors
+	ErrLoginLocked    = errors.New("too many failed login attempts, try again later")
+	ErrLoginThrottled = errors.New("login attempt is too early after failed one")
+)
+
+// pendingWait is how long attempts wait while the one that may lock the IP
+// or the account is still checking its credentials.
+const pendingWait = time.Second
+
+type LoginGuardPolicy struct {
+	MaxFailuresPerIP      int
+	MaxFailuresPerAccount int
+	FreeAttempts          int
+	BaseDelay             time.Duration
+	MaxDelay              time.Duration
+	LockoutTime           time.Duration
+	FailureWindow         time.Duration
+}
+
+type failureCounter struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+type LoginGuardService struct {
+	mu        sync.Mutex
+	policy    LoginGuardPolicy
+	byIP      map[string]*failureCounter
+	byAccount map[string]*failureCounter
+	lastSweep time.Time
+	events    ports.SecurityLog
+	now       func() time.Time
+}
+
+func NewLoginGuardService(policy LoginGuardPolicy, events ports.SecurityLog) *LoginGuardService {
+	return &LoginGuardService{
+		policy:    policy,
+		byIP:      make(map[string]*failureCounter),
+		byAccount: make(map[string]*failureCounter),
+		events:    events,
+		now:       time.Now,
+	}
+}
+
+// Check returns how long the client has to wait before the next attempt
+// together with ErrLoginLocked or ErrLoginThrottled, or zero and nil if the
+// attempt is allowed. An allowed attempt is reserved as a failure until
+// Failed, Succeeded or Released, so parallel attempts can't all pass before
+// any of them fails.
+func (svc *LoginGuardService) Check(ctx context.Context, ip, email string) (time.Duration, error) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	email = accountKey(email)
+	now := svc.now()
+	var locked, throttled time.Duration
+	for _, k := range []struct {
+		c   *failureCounter
+		max int
+	}{{svc.byIP[ip], svc.policy.MaxFailuresPerIP}, {svc.byAccount[email], svc.policy.MaxFailuresPerAccount}} {
+		if k.c == nil || svc.expired(k.c, now) {
+			continue
+		}
+		if k.c.lockedUntil.After(now) {
+			locked = max(locked, k.c.lockedUntil.Sub(now))
+			continue
+		}
+		throttled = max(throttled, k.c.lastFailure.Add(svc.delay(k.c.failures)).Sub(now))
+		// the attempt that may lock the counter is still running
+		if k.max > 0 && k.c.failures >= k.max {
+			throttled = max(throttled, pendingWait)
+		}
+	}
+	if locked > 0 {
+		return locked, ErrLoginLocked
+	}
+	if throttled > 0 {
+		return throttled, ErrLoginThrottled
+	}
+
+	svc.sweep(now)
+	svc.reserve(svc.counter(svc.byIP, ip), now)
+	svc.reserve(svc.counter(svc.byAccount, email), now)
+	return 0, nil
+}
+
+// Failed confirms the reserved attempt as a failure and locks the IP or the
+// account once it has too many.
+func (svc *LoginGuardService) Failed(ctx context.Context, ip, email string) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	email = accountKey(email)
+	now := svc.now()
+	if c, ok := svc.byIP[ip]; ok && svc.lock(c, svc.policy.MaxFailuresPerIP, now) {
+		svc.write(ctx, domain.SecurityEvent{Time: now, Type: domain.SecurityEventLockout, IP: ip, Failures: c.failures, LockedUntil: c.lockedUntil})
+	}
+	if c, ok := svc.byAccount[email]; ok && svc.lock(c, svc.policy.MaxFailuresPerAccount, now) {
+		svc.write(ctx, domain.SecurityEvent{Time: now, Type: domain.SecurityEventLockout, Email: email, Failures: c.failures, LockedUntil: c.lockedUntil})
+	}
+}
+
+// Released gives the reserved attempt back, when it failed for a reason
+// other than the credentials.
+func (svc *LoginGuardService) Released(ctx context.Context, ip, email string) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.release(svc.byIP, ip)
+	svc.release(svc.byAccount, accountKey(email))
+}
+
+// Succeeded forgets failures of the account and the attempt reserved for the
+// IP. Other failures of the IP are kept until they expire, so a single valid
+// account can't be used to reset the counter.
+func (svc *LoginGuardService) Succeeded(ctx context.Context, ip, email string) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	email = accountKey(email)
+	failures := 0
+	if c, ok := svc.byAccount[email]; ok {
+		failures = c.failures - 1
+		delete(svc.byAccount, email)
+	}
+	if c, ok := svc.byIP[ip]; ok {
+		svc.release(svc.byIP, ip)
+		failures = max(failures, c.failures)
+	}
+	if failures > 0 {
+		svc.write(ctx, domain.SecurityEvent{Time: svc.now(), Type: domain.SecurityEventLoginAfterFailures, Email: email, IP: ip, Failures: failures})
+	}
+}
+
+// accountKey normalises the email, so the case of a login does not give it
+// another counter.
+func accountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (svc *LoginGuardService) counter(m map[string]*failureCounter, key string) *failureCounter {
+	c, ok := m[key]
+	if !ok {
+		c = &failureCounter{}
+		m[key] = c
+	}
+	return c
+}
+
+func (svc *LoginGuardService) reserve(c *failureCounter, now time.Time) {
+	if svc.expired(c, now) {
+		*c = failureCounter{}
+	}
+	c.failures++
+	c.lastFailure = now
+}
+
+func (svc *LoginGuardService) release(m map[string]*failureCounter, key string) {
+	c, ok := m[key]
+	if !ok {
+		return
+	}
+	c.failures--
+	if c.failures <= 0 {
+		delete(m, key)
+	}
+}
+
+func (svc *LoginGuardService) lock(c *failureCounter, max int, now time.Time) bool {
+	if max > 0 && c.failures >= max && !c.lockedUntil.After(now) {
+		c.lockedUntil = now.Add(svc.policy.LockoutTime)
+		return true
+	}
+	return false
+}
+
+func (svc *LoginGuardService) delay(failures int) time.Duration {
+	n := failures - svc.policy.FreeAttempts
+	if n <= 0 || svc.policy.BaseDelay <= 0 {
+		return 0
+	}
+	d := svc.policy.BaseDelay
+	for i := 1; i < n; i++ {
+		d *= 2
+		if svc.policy.MaxDelay > 0 && d >= svc.policy.MaxDelay {
+			return svc.policy.MaxDelay
+		}
+	}
+	return d
+}
+
+func (svc *LoginGuardService) expired(c *failureCounter, now time.Time) bool {
+	if c.lockedUntil.After(now) {
+		return false
+	}
+	if !c.lockedUntil.IsZero() {
+		return true
+	}
+	return c.lastFailure.Add(svc.policy.FailureWindow).Before(now)
+}
+
+func (svc *LoginGuardService) sweep(now time.Time) {
+	if now.Sub(svc.lastSweep) < svc.policy.FailureWindow {
+		return
+	}
+	svc.lastSweep = now
+	for _, m := range []map[string]*failureCounter{svc.byIP, svc.byAccount} {
+		for key, c := range m {
+			if svc.expired(c, now) {
+				delete(m, key)
+			}
+		}
+	}
+}
+
+func (svc *LoginGuardService) write(ctx context.Context, event domain.SecurityEvent) {
+	if svc.events == nil {
+		return
+	}
+	if err := svc.events.Write(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "error write security event", "error", err)
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"yadro-project/internal/core/domain"
+)
+
+func newTestGuard(policy LoginGuardPolicy) (*LoginGuardService, *time.Time) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	svc := NewLoginGuardService(policy, nil)
+	svc.now = func() time.Time { return now }
+	return svc, &now
+}
+
+func TestLoginGuardService_DelayGrows(t *testing.T) {
+	svc, now := newTestGuard(LoginGuardPolicy{
+		FreeAttempts:  1,
+		BaseDelay:     time.Second,
+		MaxDelay:      4 * time.Second,
+		FailureWindow: time.Hour,
+	})
+	ctx := context.Background()
+
+	if _, err := svc.Check(ctx, "10.0.0.1", "user@test.com"); err != nil {
+		t.Fatalf("free attempt: %v", err)
+	}
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		if _, err := svc.Check(ctx, "10.0.0.1", "user@test.com"); err != nil {
+			t.Fatalf("attempt after waiting: %v", err)
+		}
+		wait, err := svc.Check(ctx, "10.0.0.1", "user@test.com")
+		if !errors.Is(err, ErrLoginThrottled) || wait != want {
+			t.Fatalf("got wait %s and error %v, want %s throttled", wait, err, want)
+		}
+		*now = now.Add(wait)
+	}
+}
+
+func TestLoginGuardService_Lockout(t *testing.T) {
+	svc, now := newTestGuard(LoginGuardPolicy{
+		MaxFailuresPerAccount: 3,
+		FreeAttempts:          10,
+		LockoutTime:           time.Minute,
+		FailureWindow:         time.Hour,
+	})
+	ctx := context.Background()
+
+	for i := range 3 {
+		// the case of the email does not give it another counter
+		ip := "10.0.0." + string(rune('1'+i))
+		if _, err := svc.Check(ctx, ip, " User@Test.com"); err != nil {
+			t.Fatalf("attempt %d: %v", i, err)
+		}
+		svc.Failed(ctx, ip, " User@Test.com")
+	}
+	wait, err := svc.Check(ctx, "10.0.0.9", "user@test.com")
+	if !errors.Is(err, ErrLoginLocked) || wait != time.Minute {
+		t.Fatalf("got wait %s and error %v, want locked for a minute", wait, err)
+	}
+
+	*now = now.Add(time.Minute)
+	if _, err := svc.Check(ctx, "10.0.0.9", "user@test.com"); err != nil {
+		t.Fatalf("after lockout: %v", err)
+	}
+	svc.Succeeded(ctx, "10.0.0.9", "USER@test.com")
+	if _, ok := svc.byAccount["user@test.com"]; ok {
+		t.Error("success kept failures of the account")
+	}
+	if _, ok := svc.byIP["10.0.0.9"]; ok {
+		t.Error("success kept the attempt reserved for the IP")
+	}
+}
+
+func TestLoginGuardService_LockOnlyConfirmedFailures(t *testing.T) {
+	events := &memSecurityLog{}
+	svc, _ := newTestGuard(LoginGuardPolicy{
+		MaxFailuresPerAccount: 2,
+		FreeAttempts:          10,
+		LockoutTime:           time.Minute,
+		FailureWindow:         time.Hour,
+	})
+	svc.events = events
+	ctx := context.Background()
+
+	if _, err := svc.Check(ctx, "10.0.0.1", "user@test.com"); err != nil {
+		t.Fatalf("first attempt: %v", err)
+	}
+	svc.Failed(ctx, "10.0.0.1", "user@test.com")
+	// the attempt reaching the limit is not locked out before it fails
+	if _, err := svc.Check(ctx, "10.0.0.1", "user@test.com"); err != nil {
+		t.Fatalf("second attempt: %v", err)
+	}
+	if _, err := svc.Check(ctx, "10.0.0.2", "user@test.com"); !errors.Is(err, ErrLoginThrottled) {
+		t.Fatalf("got %v while the second attempt runs, want throttled", err)
+	}
+	svc.Succeeded(ctx, "10.0.0.1", "user@test.com")
+	for _, e := range events.events {
+		if e.Type == domain.SecurityEventLockout {
+			t.Fatalf("successful attempt logged a lockout: %+v", e)
+		}
+	}
+
+	// an attempt failing on the server doesn't count
+	for i := range 3 {
+		if _, err := svc.Check(ctx, "10.0.0.1", "user@test.com"); err != nil {
+			t.Fatalf("attempt %d with server error: %v", i, err)
+		}
+		svc.Released(ctx, "10.0.0.1", "user@test.com")
+	}
+	if _, ok := svc.byAccount["user@test.com"]; ok {
+		t.Error("released attempts are kept for the account")
+	}
+	// the IP keeps only its first failure
+	if c := svc.byIP["10.0.0.1"]; c == nil || c.failures != 1 {
+		t.Errorf("got IP counter %+v, want 1 failure", c)
+	}
+}
+
+type memSecurityLog struct {
+	events []domain.SecurityEvent
+}
+
+func (l *memSecurityLog) Write(ctx context.Context, event domain.SecurityEvent) error {
+	l.events = append(l.events, event)
+	return nil
+}
+
+func TestLoginGuardService_ParallelAttempts(t *testing.T) {
+	svc, _ := newTestGuard(LoginGuardPolicy{
+		MaxFailuresPerIP: 5,
+		FreeAttempts:     2,
+		BaseDelay:        time.Second,
+		LockoutTime:      time.Minute,
+		FailureWindow:    time.Hour,
+	})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.Check(context.Background(), "10.0.0.1", "user@test.com"); err == nil {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	// the free attempts and one more before the delay applies
+	if allowed != 3 {
+		t.Errorf("got %d parallel attempts allowed, want 3", allowed)
+	}
+}
+
+func TestLoginGuardService_Sweep(t *testing.T) {
+	svc, now := newTestGuard(LoginGuardPolicy{
+		FreeAttempts:  10,
+		FailureWindow: time.Minute,
+	})
+	ctx := context.Background()
+
+	svc.Check(ctx, "10.0.0.1", "old@test.com")
+	*now = now.Add(2 * time.Minute)
+	svc.Check(ctx, "10.0.0.2", "new@test.com")
+
+	if len(svc.byIP) != 1 || len(svc.byAccount) != 1 {
+		t.Fatalf("got %d IPs and %d accounts after sweep, want 1 and 1", len(svc.byIP), len(svc.byAccount))
+	}
+	if _, ok := svc.byAccount["new@test.com"]; !ok {
+		t.Error("sweep removed the fresh account")
+	}
+}
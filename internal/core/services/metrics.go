@@ -0,0 +1,25 @@
+package services
+
+import (
+	"time"
+	"yadro-project/internal/core/ports"
+)
+
+// noMetrics is used when a service is created without metrics.
+type noMetrics struct{}
+
+func (noMetrics) ObserveSearch(string, time.Duration) {}
+func (noMetrics) SearchCacheLookup(string)            {}
+func (noMetrics) SetSearchCacheEntries(int)           {}
+func (noMetrics) ObserveCrawl(string, time.Duration)  {}
+func (noMetrics) ScheduledUpdate(string)              {}
+func (noMetrics) AddAdmissionActive(int)              {}
+func (noMetrics) AddAdmissionQueued(int)              {}
+func (noMetrics) AdmissionRejected(string)            {}
+
+func orNoMetrics(m ports.Metrics) ports.Metrics {
+	if m == nil {
+		return noMetrics{}
+	}
+	return m
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MaxRelatedLimit is the most related comics kept for each comics.
+const MaxRelatedLimit = 50
+
+const relatedBuildTimeout = time.Minute
+
+// RelatedService finds similar comics by cosine similarity of TF-IDF vectors
+// of their stored keywords. The model is built once per comics version, so
+// it follows updates of every instance, and neighbours are cached in it.
+type RelatedService struct {
+	repo   ports.ComicsRepository
+	comics *ComicsService
+	model  atomic.Pointer[tfidfModel]
+
+	mu       sync.Mutex
+	building chan struct{}
+	buildErr error
+}
+
+func NewRelatedService(repo ports.ComicsRepository, comics *ComicsService) *RelatedService {
+	return &RelatedService{
+		repo:   repo,
+		comics: comics,
+	}
+}
+
+// Related returns up to limit comics most similar to the comics with the ID,
+// most similar first. It returns ports.ErrIsNotExist for an unknown ID.
+func (srv *RelatedService) Related(ctx context.Context, ID, limit int) (related []domain.RelatedComics, err error) {
+	ctx, span := tracer.Start(ctx, "RelatedService.Related")
+	defer endSpan(span, &err)
+	model, err := srv.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	related, ok := model.related(ID)
+	if !ok {
+		return nil, ports.ErrIsNotExist
+	}
+	span.SetAttributes(attribute.Int("related.count", min(limit, len(related))))
+	return slices.Clone(related[:min(limit, len(related))]), nil
+}
+
+// current returns the model of the current comics version. When the comics
+// change, the model is rebuilt in the background and the previous one serves
+// until the new one is swapped in. Only the first model is waited for.
+func (srv *RelatedService) current(ctx context.Context) (*tfidfModel, error) {
+	version, err := srv.comics.Version(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error get comics version: %w", err)
+	}
+	model := srv.model.Load()
+	if model != nil && model.version.Equal(version) {
+		return model, nil
+	}
+	done := srv.rebuild(ctx, version)
+	if model != nil {
+		return model, nil
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if model = srv.model.Load(); model == nil {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		return nil, srv.buildErr
+	}
+	return model, nil
+}
+
+// rebuild starts building the model of the version unless a build is running
+// and returns a channel closed once the running build is done.
+func (srv *RelatedService) rebuild(ctx context.Context, version domain.ComicsVersion) <-chan struct{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.building != nil {
+		return srv.building
+	}
+	done := make(chan struct{})
+	srv.building = done
+	// the build outlives the request that noticed the change
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), relatedBuildTimeout)
+	go func() {
+		defer cancel()
+		err := srv.build(ctx, version)
+		if err != nil {
+			slog.ErrorContext(ctx, "error build related model", "error", err)
+		}
+		srv.mu.Lock()
+		srv.building, srv.buildErr = nil, err
+		srv.mu.Unlock()
+		close(done)
+	}()
+	return done
+}
+
+func (srv *RelatedService) build(ctx context.Context, version domain.ComicsVersion) (err error) {
+	ctx, span := tracer.Start(ctx, "RelatedService.build")
+	defer endSpan(span, &err)
+	comics, err := srv.repo.GetComics(ctx)
+	if err != nil {
+		return fmt.Errorf("error get comics from repository: %w", err)
+	}
+	srv.model.Store(newTFIDFModel(version, comics))
+	span.SetAttributes(attribute.Int("related.comics", len(comics)))
+	return nil
+}
+
+// tfidfModel holds the vectors of all comics. A comics has a keyword at most
+// once, so a vector component is the idf of the keyword.
+type tfidfModel struct {
+	version  domain.ComicsVersion
+	comics   map[int]domain.Comics
+	idf      map[string]float64
+	postings map[string][]int
+	norms    map[int]float64
+
+	mu        sync.Mutex
+	neighbors map[int][]domain.RelatedComics
+}
+
+func newTFIDFModel(version domain.ComicsVersion, comics []domain.Comics) *tfidfModel {
+	m := &tfidfModel{
+		version:   version,
+		comics:    make(map[int]domain.Comics, len(comics)),
+		idf:       make(map[string]float64),
+		postings:  make(map[string][]int),
+		norms:     make(map[int]float64, len(comics)),
+		neighbors: make(map[int][]domain.RelatedComics),
+	}
+	for _, c := range comics {
+		c.Keywords = slices.Clone(c.Keywords)
+		slices.Sort(c.Keywords)
+		c.Keywords = slices.Compact(c.Keywords)
+		m.comics[c.ID] = c
+		for _, k := range c.Keywords {
+			m.postings[k] = append(m.postings[k], c.ID)
+		}
+	}
+	// smoothed, so keywords of every comics still count a little
+	n := float64(len(comics))
+	for k, ids := range m.postings {
+		m.idf[k] = math.Log((1+n)/(1+float64(len(ids)))) + 1
+	}
+	for id, c := range m.comics {
+		sum := 0.0
+		for _, k := range c.Keywords {
+			sum += m.idf[k] * m.idf[k]
+		}
+		m.norms[id] = math.Sqrt(sum)
+	}
+	return m
+}
+
+// related returns the nearest MaxRelatedLimit comics, computing them on the
+// first call. Only comics sharing a keyword are scored.
+func (m *tfidfModel) related(ID int) ([]domain.RelatedComics, bool) {
+	c, ok := m.comics[ID]
+	if !ok {
+		return nil, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if related, ok := m.neighbors[ID]; ok {
+		return related, true
+	}
+
+	dots := make(map[int]float64)
+	for _, k := range c.Keywords {
+		w := m.idf[k] * m.idf[k]
+		for _, other := range m.postings[k] {
+			if other != ID {
+				dots[other] += w
+			}
+		}
+	}
+	related := make([]domain.RelatedComics, 0, len(dots))
+	for other, dot := range dots {
+		related = append(related, domain.RelatedComics{
+			ID:     other,
+			ImgURL: m.comics[other].ImgURL,
+			Score:  min(dot/(m.norms[ID]*m.norms[other]), 1),
+		})
+	}
+	slices.SortFunc(related, func(a, b domain.RelatedComics) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	related = slices.Clip(related[:min(MaxRelatedLimit, len(related))])
+	m.neighbors[ID] = related
+	return related, true
+}
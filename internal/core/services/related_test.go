@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+)
+
+func TestTFIDFModel_Related(t *testing.T) {
+	m := newTFIDFModel(domain.ComicsVersion{}, []domain.Comics{
+		{ID: 1, Keywords: []string{"python", "tree", "comput"}},
+		{ID: 2, Keywords: []string{"python", "tree"}},
+		{ID: 3, Keywords: []string{"python", "barrel"}},
+		{ID: 4, Keywords: []string{"barrel", "boy"}},
+		{ID: 5, Keywords: []string{"python"}},
+	})
+
+	related, ok := m.related(1)
+	if !ok {
+		t.Fatal("comics 1 is not in the model")
+	}
+	got := make([]int, 0, len(related))
+	for _, r := range related {
+		got = append(got, r.ID)
+		if r.Score <= 0 || r.Score > 1 {
+			t.Errorf("comics %d has score %g out of (0, 1]", r.ID, r.Score)
+		}
+	}
+	// 2 shares the rare "tree", 5 has nothing but "python", 4 shares nothing
+	want := []int{2, 5, 3}
+	if len(got) != len(want) {
+		t.Fatalf("got related %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got related %v, want %v", got, want)
+		}
+	}
+
+	if _, ok := m.related(42); ok {
+		t.Error("unknown comics has related comics")
+	}
+}
+
+// relatedRepo serves the comics, GetComics waits for release while it is set.
+type relatedRepo struct {
+	ports.ComicsRepository
+	mu         sync.Mutex
+	comics     []domain.Comics
+	updateTime time.Time
+	release    chan struct{}
+}
+
+func (r *relatedRepo) GetComics(ctx context.Context) ([]domain.Comics, error) {
+	r.mu.Lock()
+	comics, release := r.comics, r.release
+	r.mu.Unlock()
+	if release != nil {
+		<-release
+	}
+	return comics, nil
+}
+
+func (r *relatedRepo) GetLastUpdateTime(ctx context.Context) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.updateTime, nil
+}
+
+type generationIndex struct {
+	ports.Indexer
+}
+
+func (generationIndex) GetGeneration(ctx context.Context) (int, error) {
+	return 1, nil
+}
+
+func TestRelatedService_ServesPreviousModelWhileBuilding(t *testing.T) {
+	repo := &relatedRepo{comics: []domain.Comics{
+		{ID: 1, Keywords: []string{"python"}},
+		{ID: 2, Keywords: []string{"python"}},
+	}}
+	cSVC := NewComicsService(repo, nil, generationIndex{}, nil, 0, 0, 0, nil)
+	srv := NewRelatedService(repo, cSVC)
+	ctx := context.Background()
+	if related, err := srv.Related(ctx, 1, 10); err != nil || len(related) != 1 {
+		t.Fatalf("got %v, %v from the first model", related, err)
+	}
+
+	release := make(chan struct{})
+	repo.mu.Lock()
+	repo.comics = append(repo.comics, domain.Comics{ID: 3, Keywords: []string{"python"}})
+	repo.updateTime = repo.updateTime.Add(time.Second)
+	repo.release = release
+	repo.mu.Unlock()
+	cSVC.changed()
+
+	// the build waits for release, the previous model answers meanwhile
+	if related, err := srv.Related(ctx, 1, 10); err != nil || len(related) != 1 {
+		t.Fatalf("got %v, %v while the model is rebuilt, want the previous model", related, err)
+	}
+	srv.mu.Lock()
+	done := srv.building
+	srv.mu.Unlock()
+	if done == nil {
+		t.Fatal("changed comics did not start a build")
+	}
+	close(release)
+	<-done
+	if related, err := srv.Related(ctx, 1, 10); err != nil || len(related) != 2 {
+		t.Fatalf("got %v, %v after the build, want the new model", related, err)
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+)
+
+var ( //errors
+	ErrUpdateInProgress = errors.New("update is already in progress")
+)
+
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 500
+)
+
+// Schedule returns the next activation time after the given one, e.g. a
+// parsed cron expression.
+type Schedule interface {
+	Next(time.Time) time.Time
+}
+
+// SchedulerService runs updates on a schedule. Each run starts up to jitter
+// after the scheduled time, so clients of xkcd don't wake up at the same
+// moment. Only the elected leader runs updates.
+type SchedulerService struct {
+	comics   *ComicsService
+	election *ElectionService
+	mutex    *sync.Mutex
+	schedule Schedule
+	jitter   time.Duration
+	history  ports.UpdateHistoryRepository
+	audit    *AuditService
+	metrics  ports.Metrics
+}
+
+func NewSchedulerService(comics *ComicsService, election *ElectionService, mutex *sync.Mutex, schedule Schedule, jitter time.Duration, history ports.UpdateHistoryRepository, audit *AuditService, metrics ports.Metrics) *SchedulerService {
+	return &SchedulerService{
+		comics:   comics,
+		election: election,
+		mutex:    mutex,
+		schedule: schedule,
+		jitter:   jitter,
+		history:  history,
+		audit:    audit,
+		metrics:  orNoMetrics(metrics),
+	}
+}
+
+func (svc *SchedulerService) Run(ctx context.Context) {
+	for {
+		scheduled := svc.schedule.Next(time.Now())
+		at := scheduled.Add(svc.randJitter())
+		slog.InfoContext(ctx, "next scheduled update", "at", at)
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if !svc.election.IsLeader() {
+			slog.DebugContext(ctx, "scheduled update left to the leader")
+			continue
+		}
+		svc.run(ctx, scheduled)
+	}
+}
+
+// run updates comics unless an update is already running, in which case the
+// run is recorded as skipped.
+func (svc *SchedulerService) run(ctx context.Context, scheduled time.Time) domain.UpdateRun {
+	run := domain.UpdateRun{
+		ScheduledAt: scheduled,
+		StartedAt:   time.Now(),
+		Outcome:     domain.UpdateRunSuccess,
+	}
+	event := domain.AuditEvent{
+		Actor:   "scheduler",
+		Action:  domain.AuditActionUpdate,
+		Outcome: domain.AuditOutcomeSuccess,
+	}
+	leadCtx, cancel, err := svc.election.Lead(ctx)
+	switch {
+	case err != nil:
+		run.Outcome, run.Error = domain.UpdateRunSkipped, err.Error()
+		event.Outcome, event.Details = domain.AuditOutcomeDenied, err.Error()
+	case svc.mutex.TryLock():
+		meta, err := svc.comics.UpdateComics(leadCtx)
+		svc.mutex.Unlock()
+		if err != nil {
+			run.Outcome, run.Error = domain.UpdateRunFailure, err.Error()
+			event.Outcome, event.Details = domain.AuditOutcomeFailure, err.Error()
+		} else {
+			run.New, run.Total = meta.New, meta.Total
+			event.Details = fmt.Sprintf("new: %d, total: %d", meta.New, meta.Total)
+		}
+	default:
+		run.Outcome, run.Error = domain.UpdateRunSkipped, ErrUpdateInProgress.Error()
+		event.Outcome, event.Details = domain.AuditOutcomeDenied, ErrUpdateInProgress.Error()
+	}
+	if cancel != nil {
+		cancel()
+	}
+	run.FinishedAt = time.Now()
+
+	svc.metrics.ScheduledUpdate(run.Outcome)
+	slog.InfoContext(ctx, "scheduled update finished", "outcome", run.Outcome, "new", run.New, "total", run.Total, "error", run.Error)
+	if err := svc.history.Append(ctx, run); err != nil {
+		slog.ErrorContext(ctx, "error append update run", "error", err)
+	}
+	svc.audit.Record(ctx, event)
+	return run
+}
+
+func (svc *SchedulerService) randJitter() time.Duration {
+	if svc.jitter <= 0 {
+		return 0
+	}
+	return rand.N(svc.jitter)
+}
+
+func (svc *SchedulerService) History(ctx context.Context, limit int) ([]domain.UpdateRun, error) {
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	runs, err := svc.history.Find(ctx, min(limit, maxHistoryLimit))
+	if err != nil {
+		return nil, fmt.Errorf("error find update runs: %w", err)
+	}
+	return runs, nil
+}
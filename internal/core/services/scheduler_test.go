@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+	"yadro-project/internal/core/domain"
+)
+
+type runHistory struct {
+	runs []domain.UpdateRun
+}
+
+func (h *runHistory) Append(ctx context.Context, run domain.UpdateRun) error {
+	h.runs = append(h.runs, run)
+	return nil
+}
+
+func (h *runHistory) Find(ctx context.Context, limit int) ([]domain.UpdateRun, error) {
+	return h.runs, nil
+}
+
+type auditLog struct {
+	events []domain.AuditEvent
+}
+
+func (l *auditLog) Append(ctx context.Context, event domain.AuditEvent) error {
+	l.events = append(l.events, event)
+	return nil
+}
+
+func (l *auditLog) Find(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent, error) {
+	return l.events, nil
+}
+
+func TestSchedulerService_SkipsWhileUpdating(t *testing.T) {
+	history, audit := &runHistory{}, &auditLog{}
+	mutex := &sync.Mutex{}
+	election := NewElectionService(&stubLease{ok: true}, "test", time.Minute)
+	election.Campaign(context.Background())
+	svc := NewSchedulerService(nil, election, mutex, nil, 0, history, NewAuditService(audit), nil)
+
+	mutex.Lock()
+	scheduled := time.Date(2026, 10, 19, 3, 0, 0, 0, time.UTC)
+	run := svc.run(context.Background(), scheduled)
+	mutex.Unlock()
+
+	if run.Outcome != domain.UpdateRunSkipped || !run.ScheduledAt.Equal(scheduled) {
+		t.Errorf("got run %+v, want a skipped run scheduled at %s", run, scheduled)
+	}
+	if len(history.runs) != 1 || len(audit.events) != 1 || audit.events[0].Outcome != domain.AuditOutcomeDenied {
+		t.Errorf("got history %+v and audit %+v, want the skipped run in both", history.runs, audit.events)
+	}
+}
+
+func TestSchedulerService_Jitter(t *testing.T) {
+	svc := NewSchedulerService(nil, nil, nil, nil, time.Minute, nil, nil, nil)
+	for i := 0; i < 100; i++ {
+		if d := svc.randJitter(); d < 0 || d >= time.Minute {
+			t.Fatalf("got jitter %s, want it in [0, 1m)", d)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"container/list"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"yadro-project/internal/core/domain"
+	"yadro-project/internal/core/ports"
+)
+
+// searchCache is an LRU of search results. Entries are kept with the comics
+// version they were computed from and only returned for it, so an update by
+// any instance makes them stale. They also expire after ttl.
+type searchCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List
+	metrics ports.Metrics
+}
+
+type searchCacheEntry struct {
+	key     string
+	version domain.ComicsVersion
+	comics  []domain.Comics
+	expires time.Time
+}
+
+func newSearchCache(size int, ttl time.Duration, metrics ports.Metrics) *searchCache {
+	return &searchCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		metrics: orNoMetrics(metrics),
+	}
+}
+
+// searchKey normalises the stemmed keywords, their order and repeats do not
+// change the result.
+func searchKey(n int, keywords []string) string {
+	keywords = slices.Clone(keywords)
+	slices.Sort(keywords)
+	keywords = slices.Compact(keywords)
+	return strconv.Itoa(n) + ":" + strings.Join(keywords, " ")
+}
+
+// get returns the result cached for the version.
+func (c *searchCache) get(version domain.ComicsVersion, key string) ([]domain.Comics, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*searchCacheEntry)
+		if e.version.Equal(version) && time.Now().Before(e.expires) {
+			c.order.MoveToFront(el)
+			c.metrics.SearchCacheLookup("hit")
+			return slices.Clone(e.comics), true
+		}
+		c.remove(el)
+	}
+	c.metrics.SearchCacheLookup("miss")
+	return nil, false
+}
+
+func (c *searchCache) put(version domain.ComicsVersion, key string, comics []domain.Comics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size <= 0 {
+		return
+	}
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	c.entries[key] = c.order.PushFront(&searchCacheEntry{
+		key:     key,
+		version: version,
+		comics:  slices.Clone(comics),
+		expires: time.Now().Add(c.ttl),
+	})
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+	c.metrics.SetSearchCacheEntries(c.order.Len())
+}
+
+// invalidate drops the entries once this instance changed the comics, they
+// would not be returned anymore.
+func (c *searchCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.order.Init()
+	c.metrics.SetSearchCacheEntries(0)
+}
+
+func (c *searchCache) remove(el *list.Element) {
+	delete(c.entries, el.Value.(*searchCacheEntry).key)
+	c.order.Remove(el)
+	c.metrics.SetSearchCacheEntries(c.order.Len())
+}
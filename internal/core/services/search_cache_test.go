@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"yadro-project/internal/core/domain"
+)
+
+func TestSearchKey_IgnoresOrderAndRepeats(t *testing.T) {
+	if a, b := searchKey(10, []string{"tree", "python", "tree"}), searchKey(10, []string{"python", "tree"}); a != b {
+		t.Fatalf("keys differ: %q and %q", a, b)
+	}
+	if a, b := searchKey(10, []string{"python"}), searchKey(5, []string{"python"}); a == b {
+		t.Fatalf("keys for different limits are equal: %q", a)
+	}
+}
+
+var testVersion = domain.ComicsVersion{Generation: 1, UpdatedAt: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)}
+
+func TestSearchCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newSearchCache(2, time.Minute, nil)
+	c.put(testVersion, "a", []domain.Comics{{ID: 1}})
+	c.put(testVersion, "b", []domain.Comics{{ID: 2}})
+	c.get(testVersion, "a")
+	c.put(testVersion, "c", []domain.Comics{{ID: 3}})
+
+	if _, ok := c.get(testVersion, "b"); ok {
+		t.Fatal("b was used least recently and should be evicted")
+	}
+	if comics, ok := c.get(testVersion, "a"); !ok || comics[0].ID != 1 {
+		t.Fatalf("got %v, %t, want a cached", comics, ok)
+	}
+}
+
+func TestSearchCache_Expires(t *testing.T) {
+	c := newSearchCache(2, time.Nanosecond, nil)
+	c.put(testVersion, "a", []domain.Comics{{ID: 1}})
+	time.Sleep(time.Millisecond)
+	if _, ok := c.get(testVersion, "a"); ok {
+		t.Fatal("expired entry was returned")
+	}
+}
+
+func TestSearchCache_OtherVersionMisses(t *testing.T) {
+	c := newSearchCache(2, time.Minute, nil)
+	c.put(testVersion, "a", []domain.Comics{{ID: 1}})
+	// another instance updated the comics
+	next := domain.ComicsVersion{Generation: testVersion.Generation, UpdatedAt: testVersion.UpdatedAt.Add(time.Second)}
+	if _, ok := c.get(next, "a"); ok {
+		t.Fatal("result of the previous version was returned")
+	}
+}
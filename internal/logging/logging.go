@@ -0,0 +1,140 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ( //errors
+	ErrUnknownFormat = errors.New("unknown log format")
+	ErrUnknownLevel  = errors.New("unknown log level")
+)
+
+const ( // formats
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+var level = new(slog.LevelVar)
+
+// New builds a logger writing in the given format. The level is shared by all
+// loggers built here and can be changed with SetLevel.
+func New(w io.Writer, format, lvl string) (*slog.Logger, error) {
+	if err := SetLevel(lvl); err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case FormatJSON, "":
+		h = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	return slog.New(contextHandler{h}), nil
+}
+
+func SetLevel(lvl string) error {
+	var l slog.Level
+	if lvl == "" {
+		lvl = "info"
+	}
+	if err := l.UnmarshalText([]byte(lvl)); err != nil {
+		return fmt.Errorf("%w: %q", ErrUnknownLevel, lvl)
+	}
+	level.Set(l)
+	return nil
+}
+
+type ctxKey struct{}
+
+// Request holds what is known about the request being served. The fields are
+// filled in by different middlewares, so it is shared by pointer.
+type Request struct {
+	mu    sync.Mutex
+	id    string
+	user  string
+	route string
+}
+
+func (r *Request) ID() string {
+	return r.id
+}
+
+func (r *Request) SetUser(user string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.user = user
+}
+
+func (r *Request) User() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.user
+}
+
+func (r *Request) SetRoute(route string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.route = route
+}
+
+func (r *Request) Route() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.route
+}
+
+func WithRequest(ctx context.Context, id string) (context.Context, *Request) {
+	req := &Request{id: id}
+	return context.WithValue(ctx, ctxKey{}, req), req
+}
+
+// FromContext returns the request stored in ctx or nil.
+func FromContext(ctx context.Context) *Request {
+	req, _ := ctx.Value(ctxKey{}).(*Request)
+	return req
+}
+
+func RequestID(ctx context.Context) string {
+	if req := FromContext(ctx); req != nil {
+		return req.id
+	}
+	return ""
+}
+
+// contextHandler adds the request id, user and trace id from the context to
+// records.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if req := FromContext(ctx); req != nil {
+		r.AddAttrs(slog.String("request_id", req.id))
+		if user := req.User(); user != "" {
+			r.AddAttrs(slog.String("user", user))
+		}
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "xkcd"
+
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+var ( // http
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route and status code.",
+	}, []string{"route", "status"})
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "status"})
+)
+
+var ( // search
+	SearchDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "search_duration_seconds",
+		Help:      "Search latency by source: index or repository fallback.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"source"})
+	SearchCacheRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "search_cache_requests_total",
+		Help:      "Search cache lookups by result: hit or miss.",
+	}, []string{"result"})
+	SearchCacheEntries = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "search_cache_entries",
+		Help:      "Search results in the cache.",
+	})
+)
+
+var ( // crawl
+	CrawlDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "crawl_duration_seconds",
+		Help:      "Duration of fetching comics from xkcd by mode: full or part.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200},
+	}, []string{"mode"})
+	ComicsFetched = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "comics_fetched_total",
+		Help:      "Comics fetched from xkcd.",
+	})
+	ComicsFetchFailed = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "comics_fetch_failed_total",
+		Help:      "Comics that failed to be fetched from xkcd, not counting missing ones.",
+	})
+	UpstreamResponses = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_responses_total",
+		Help:      "Responses of xkcd by status code, \"error\" if the request failed.",
+	}, []string{"status"})
+	ScheduledUpdates = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scheduled_updates_total",
+		Help:      "Scheduled updates by outcome: success, failure or skipped.",
+	}, []string{"outcome"})
+)
+
+var ( // limits
+	RateLimitRejections = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected by rate limit policies by route.",
+	}, []string{"route"})
+	AdmissionActive = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "admission_active",
+		Help:      "Requests running under the concurrency limit.",
+	})
+	AdmissionQueued = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "admission_queued",
+		Help:      "Requests waiting for the concurrency limit.",
+	})
+	AdmissionRejections = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "admission_rejections_total",
+		Help:      "Requests rejected by the concurrency limiter by reason: queue_full or timeout.",
+	}, []string{"reason"})
+)
+
+var ( // auth
+	LoginFailures = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_failures_total",
+		Help:      "Failed logins by reason: bad_credentials, throttled or locked.",
+	}, []string{"reason"})
+)
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import "time"
+
+// Recorder exports what the services and the xkcd client observe.
+type Recorder struct{}
+
+func (Recorder) ObserveSearch(source string, d time.Duration) {
+	SearchDuration.WithLabelValues(source).Observe(d.Seconds())
+}
+
+func (Recorder) SearchCacheLookup(result string) {
+	SearchCacheRequests.WithLabelValues(result).Inc()
+}
+
+func (Recorder) SetSearchCacheEntries(n int) {
+	SearchCacheEntries.Set(float64(n))
+}
+
+func (Recorder) ObserveCrawl(mode string, d time.Duration) {
+	CrawlDuration.WithLabelValues(mode).Observe(d.Seconds())
+}
+
+func (Recorder) ScheduledUpdate(outcome string) {
+	ScheduledUpdates.WithLabelValues(outcome).Inc()
+}
+
+func (Recorder) AddAdmissionActive(delta int) {
+	AdmissionActive.Add(float64(delta))
+}
+
+func (Recorder) AddAdmissionQueued(delta int) {
+	AdmissionQueued.Add(float64(delta))
+}
+
+func (Recorder) AdmissionRejected(reason string) {
+	AdmissionRejections.WithLabelValues(reason).Inc()
+}
+
+func (Recorder) ComicsFetched() {
+	ComicsFetched.Inc()
+}
+
+func (Recorder) ComicsFetchFailed() {
+	ComicsFetchFailed.Inc()
+}
+
+func (Recorder) UpstreamResponse(status string) {
+	UpstreamResponses.WithLabelValues(status).Inc()
+}
@@ -5,10 +5,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"yadro-project/internal/core/domain"
 	"yadro-project/internal/core/ports"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var ( // errors
@@ -21,18 +28,52 @@ var ( //url
 	urlGetComicsByID = "https://%s/%d/info.0.json"
 )
 
+var tracer = otel.Tracer("yadro-project/pkg/xkcd")
+
+// Observer is told about fetches from xkcd, e.g. to export metrics.
+type Observer interface {
+	ComicsFetched()
+	// ComicsFetchFailed is not called for missing comics.
+	ComicsFetchFailed()
+	// UpstreamResponse gets the status code, "error" if the request failed.
+	UpstreamResponse(status string)
+}
+
+type noObserver struct{}
+
+func (noObserver) ComicsFetched()          {}
+func (noObserver) ComicsFetchFailed()      {}
+func (noObserver) UpstreamResponse(string) {}
+
 type XkcdParse struct {
 	URL      string
-	Parallel int
 	Stemmer  ports.Stemmer
+	parallel atomic.Int64
+	client   *http.Client
+	observer Observer
 }
 
-func NewXkcdParse(url string, parallel int, stemmer ports.Stemmer) *XkcdParse {
-	return &XkcdParse{
+// NewXkcdParse creates the parser. The observer may be nil.
+func NewXkcdParse(url string, parallel int, stemmer ports.Stemmer, observer Observer) *XkcdParse {
+	if observer == nil {
+		observer = noObserver{}
+	}
+	xp := &XkcdParse{
 		URL:      url,
-		Parallel: parallel,
 		Stemmer:  stemmer,
+		observer: observer,
+		client: &http.Client{
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
 	}
+	xp.SetParallel(parallel)
+	return xp
+}
+
+// SetParallel changes how many comics are fetched at once. Crawls already
+// running keep their parallelism.
+func (xp *XkcdParse) SetParallel(parallel int) {
+	xp.parallel.Store(int64(parallel))
 }
 
 func (xp *XkcdParse) stemSliceComics(parsedComics []Comics) ([]domain.Comics, error) {
@@ -48,8 +89,10 @@ func (xp *XkcdParse) stemSliceComics(parsedComics []Comics) ([]domain.Comics, er
 
 func (xp *XkcdParse) stemComics(comics Comics) (domain.Comics, error) {
 	cAns := domain.Comics{
-		ID:     comics.ID,
-		ImgURL: comics.ImgURL,
+		ID:         comics.ID,
+		ImgURL:     comics.ImgURL,
+		Transcript: comics.Transcript,
+		Alt:        comics.Alt,
 	}
 	keywords, err := xp.Stemmer.Stem(comics.GetWordsFromTranscriptAndAlt())
 	if err != nil {
@@ -77,8 +120,10 @@ type ResultWithError struct {
 }
 
 func (xp *XkcdParse) FullParse(ctx context.Context, cntInServer int) ([]domain.Comics, error) {
+	ctx, span := tracer.Start(ctx, "XkcdParse.FullParse", trace.WithAttributes(attribute.Int("comics.count", cntInServer)))
+	defer span.End()
 	s := Semaphore{
-		ch: make(chan struct{}, xp.Parallel),
+		ch: make(chan struct{}, xp.parallel.Load()),
 	}
 	outputChan := make(chan ResultWithError, cntInServer)
 	for i := 1; i <= cntInServer; i++ {
@@ -92,16 +137,18 @@ func (xp *XkcdParse) FullParse(ctx context.Context, cntInServer int) ([]domain.C
 				return
 			default:
 			}
-			c, err := xp.GetComicsByID(ID)
+			c, err := xp.GetComicsByID(ctx, ID)
 			if err != nil {
 				if errors.Is(err, ErrComicsNotFound) {
 					outputChan <- ResultWithError{Err: err}
 					return
 				}
-				log.Println(fmt.Errorf("error get info about comics with id %d: %w", ID, err))
+				xp.observer.ComicsFetchFailed()
+				slog.WarnContext(ctx, "error get info about comics", "id", ID, "error", err)
 				outputChan <- ResultWithError{Err: fmt.Errorf("error get info about comics with id %d: %w", ID+1, err)}
 				return
 			}
+			xp.observer.ComicsFetched()
 			outputChan <- ResultWithError{
 				Comics: c,
 				Err:    nil,
@@ -118,8 +165,10 @@ func (xp *XkcdParse) FullParse(ctx context.Context, cntInServer int) ([]domain.C
 }
 
 func (xp *XkcdParse) PartParse(ctx context.Context, isNotExist []int) ([]domain.Comics, error) {
+	ctx, span := tracer.Start(ctx, "XkcdParse.PartParse", trace.WithAttributes(attribute.Int("comics.count", len(isNotExist))))
+	defer span.End()
 	s := Semaphore{
-		ch: make(chan struct{}, xp.Parallel),
+		ch: make(chan struct{}, xp.parallel.Load()),
 	}
 	outputChan := make(chan ResultWithError, len(isNotExist))
 	for _, ID := range isNotExist {
@@ -133,16 +182,18 @@ func (xp *XkcdParse) PartParse(ctx context.Context, isNotExist []int) ([]domain.
 				return
 			default:
 			}
-			c, err := xp.GetComicsByID(currID)
+			c, err := xp.GetComicsByID(ctx, currID)
 			if err != nil {
 				if errors.Is(err, ErrComicsNotFound) {
 					outputChan <- ResultWithError{Err: err}
 					return
 				}
-				log.Println(fmt.Errorf("error get info about comics with id %d: %w", currID, err))
+				xp.observer.ComicsFetchFailed()
+				slog.WarnContext(ctx, "error get info about comics", "id", currID, "error", err)
 				outputChan <- ResultWithError{Err: fmt.Errorf("error get info about comics with id %d: %w", currID, err)}
 				return
 			}
+			xp.observer.ComicsFetched()
 			outputChan <- ResultWithError{
 				Comics: c,
 				Err:    nil,
@@ -172,12 +223,18 @@ func (xp *XkcdParse) ReadInArrayFromChan(outputChan <-chan ResultWithError, n in
 	return ans, nil
 }
 
-func (xp *XkcdParse) GetComicsByID(ID int) (Comics, error) {
-	resp, err := http.Get(fmt.Sprintf(urlGetComicsByID, xp.URL, ID))
+func (xp *XkcdParse) GetComicsByID(ctx context.Context, ID int) (Comics, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(urlGetComicsByID, xp.URL, ID), nil)
+	if err != nil {
+		return Comics{}, err
+	}
+	resp, err := xp.client.Do(req)
 	if err != nil {
+		xp.observer.UpstreamResponse("error")
 		return Comics{}, err
 	}
 	defer resp.Body.Close()
+	xp.observer.UpstreamResponse(strconv.Itoa(resp.StatusCode))
 	if resp.StatusCode == http.StatusNotFound {
 		return Comics{}, ErrComicsNotFound
 	}
@@ -192,10 +249,12 @@ func (xp *XkcdParse) GetComicsByID(ID int) (Comics, error) {
 }
 
 func (xp *XkcdParse) GetCountComicsInServer(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "XkcdParse.GetCountComicsInServer")
+	defer span.End()
 	start := 1
 	end := 100
 	for ; ; start, end = end, end+100 {
-		flag, err := xp.IsNotFoundComics(end, true)
+		flag, err := xp.IsNotFoundComics(ctx, end, true)
 		if err != nil {
 			return 0, err
 		}
@@ -205,7 +264,7 @@ func (xp *XkcdParse) GetCountComicsInServer(ctx context.Context) (int, error) {
 	}
 	for start < end {
 		middle := (start + end) / 2
-		flag, err := xp.IsNotFoundComics(middle, true)
+		flag, err := xp.IsNotFoundComics(ctx, middle, true)
 		if err != nil {
 			return 0, err
 		}
@@ -218,18 +277,18 @@ func (xp *XkcdParse) GetCountComicsInServer(ctx context.Context) (int, error) {
 	return end - 1, nil
 }
 
-func (xp *XkcdParse) IsNotFoundComics(ID int, isMain bool) (bool, error) {
-	_, err := xp.GetComicsByID(ID)
+func (xp *XkcdParse) IsNotFoundComics(ctx context.Context, ID int, isMain bool) (bool, error) {
+	_, err := xp.GetComicsByID(ctx, ID)
 	if err != nil {
 		if errors.Is(err, ErrComicsNotFound) {
 			if !isMain {
 				return true, nil
 			}
-			f1, err := xp.IsNotFoundComics(ID-1, false)
+			f1, err := xp.IsNotFoundComics(ctx, ID-1, false)
 			if err != nil {
 				return false, err
 			}
-			f2, err := xp.IsNotFoundComics(ID+1, false)
+			f2, err := xp.IsNotFoundComics(ctx, ID+1, false)
 			if err != nil {
 				return false, err
 			}